@@ -0,0 +1,22 @@
+package scheme
+
+import (
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/config/v1beta1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	schedscheme "k8s.io/kubernetes/pkg/scheduler/apis/config/scheme"
+)
+
+// Scheme is the scheme of the kube-scheduler configuration, extended with the
+// arguments of the placement policy plugin.
+var Scheme = schedscheme.Scheme
+
+func init() {
+	AddToScheme(Scheme)
+}
+
+// AddToScheme registers the placement policy plugin arguments to the scheme
+func AddToScheme(scheme *runtime.Scheme) {
+	utilruntime.Must(v1beta1.AddToScheme(scheme))
+}
@@ -0,0 +1,50 @@
+package v1beta1
+
+import (
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var (
+	// DefaultEnforcementMode is the default enforcement mode of the placement policies
+	DefaultEnforcementMode = v1alpha1.DefaultEnforcementMode
+	// DefaultResyncPeriod is the default resync period of the placement policy informers
+	DefaultResyncPeriod = metav1.Duration{}
+	// DefaultScoreMagnitude is the default score of the nodes of the preferred group
+	DefaultScoreMagnitude = framework.MaxNodeScore
+	// DefaultAnnotatePods is the default value of AnnotatePods
+	DefaultAnnotatePods = true
+	// DefaultEnablePreemption is the default value of EnablePreemption
+	DefaultEnablePreemption = false
+)
+
+func addDefaultingFuncs(scheme *runtime.Scheme) error {
+	return RegisterDefaults(scheme)
+}
+
+// SetDefaults_PlacementPolicyArgs sets the default parameters for the placement policy plugin.
+func SetDefaults_PlacementPolicyArgs(obj *PlacementPolicyArgs) {
+	if obj.DefaultEnforcementMode == nil {
+		mode := DefaultEnforcementMode
+		obj.DefaultEnforcementMode = &mode
+	}
+	if obj.ResyncPeriod == nil {
+		period := DefaultResyncPeriod
+		obj.ResyncPeriod = &period
+	}
+	if obj.ScoreMagnitude == nil {
+		magnitude := DefaultScoreMagnitude
+		obj.ScoreMagnitude = &magnitude
+	}
+	if obj.AnnotatePods == nil {
+		annotatePods := DefaultAnnotatePods
+		obj.AnnotatePods = &annotatePods
+	}
+	if obj.EnablePreemption == nil {
+		enablePreemption := DefaultEnablePreemption
+		obj.EnablePreemption = &enablePreemption
+	}
+}
@@ -0,0 +1,7 @@
+// +k8s:deepcopy-gen=package
+// +k8s:defaulter-gen=TypeMeta
+// +groupName=kubescheduler.config.k8s.io
+
+// Package v1beta1 contains the versioned arguments of the placement policy
+// plugin, set in the pluginConfig of the KubeSchedulerConfiguration.
+package v1beta1
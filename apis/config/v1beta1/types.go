@@ -0,0 +1,33 @@
+package v1beta1
+
+import (
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PlacementPolicyArgs holds arguments used to configure the placement policy plugin.
+type PlacementPolicyArgs struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// DefaultEnforcementMode is the enforcement mode of the placement policies
+	// that don't specify one. Defaults to BestEffort.
+	DefaultEnforcementMode *v1alpha1.EnforcementMode `json:"defaultEnforcementMode,omitempty"`
+	// ResyncPeriod is the resync period of the placement policy informers.
+	// Defaults to 0, which disables resync.
+	ResyncPeriod *metav1.Duration `json:"resyncPeriod,omitempty"`
+	// ScoreMagnitude is the score given to the nodes of the preferred group when
+	// the placement of the pods is the furthest from the target size. Lower
+	// values give more room to the other score plugins. Must be between 1 and
+	// 100. Defaults to 100.
+	ScoreMagnitude *int64 `json:"scoreMagnitude,omitempty"`
+	// AnnotatePods enables writing the placement policy and the node preference
+	// to the annotations of the scheduled pods. Defaults to true.
+	AnnotatePods *bool `json:"annotatePods,omitempty"`
+	// EnablePreemption enables preempting pods on the nodes of the preferred
+	// group when a Strict placement policy leaves the pod unschedulable.
+	// Defaults to false.
+	EnablePreemption *bool `json:"enablePreemption,omitempty"`
+}
@@ -0,0 +1,31 @@
+package v1beta1
+
+import (
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// ValidatePlacementPolicyArgs validates the defaulted arguments of the placement policy plugin.
+func ValidatePlacementPolicyArgs(path *field.Path, args *PlacementPolicyArgs) error {
+	var allErrs field.ErrorList
+
+	if args.DefaultEnforcementMode != nil {
+		switch *args.DefaultEnforcementMode {
+		case v1alpha1.EnforcementModeBestEffort, v1alpha1.EnforcementModeStrict:
+		default:
+			allErrs = append(allErrs, field.NotSupported(path.Child("defaultEnforcementMode"), *args.DefaultEnforcementMode,
+				[]string{string(v1alpha1.EnforcementModeBestEffort), string(v1alpha1.EnforcementModeStrict)}))
+		}
+	}
+	if args.ResyncPeriod != nil && args.ResyncPeriod.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("resyncPeriod"), args.ResyncPeriod.Duration.String(), "must be greater than or equal to 0"))
+	}
+	if args.ScoreMagnitude != nil && (*args.ScoreMagnitude < framework.MinNodeScore+1 || *args.ScoreMagnitude > framework.MaxNodeScore) {
+		allErrs = append(allErrs, field.Invalid(path.Child("scoreMagnitude"), *args.ScoreMagnitude,
+			"must be between 1 and 100"))
+	}
+
+	return allErrs.ToAggregate()
+}
@@ -0,0 +1,76 @@
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidatePlacementPolicyArgs(t *testing.T) {
+	validArgs := func() *PlacementPolicyArgs {
+		args := &PlacementPolicyArgs{}
+		SetDefaults_PlacementPolicyArgs(args)
+		return args
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(args *PlacementPolicyArgs)
+		wantErr bool
+	}{
+		{
+			name:   "default args",
+			mutate: func(args *PlacementPolicyArgs) {},
+		},
+		{
+			name: "strict default enforcement mode",
+			mutate: func(args *PlacementPolicyArgs) {
+				mode := v1alpha1.EnforcementModeStrict
+				args.DefaultEnforcementMode = &mode
+			},
+		},
+		{
+			name: "unknown default enforcement mode",
+			mutate: func(args *PlacementPolicyArgs) {
+				mode := v1alpha1.EnforcementMode("Force")
+				args.DefaultEnforcementMode = &mode
+			},
+			wantErr: true,
+		},
+		{
+			name:    "negative resync period",
+			mutate:  func(args *PlacementPolicyArgs) { args.ResyncPeriod = &metav1.Duration{Duration: -time.Minute} },
+			wantErr: true,
+		},
+		{
+			name: "zero score magnitude",
+			mutate: func(args *PlacementPolicyArgs) {
+				magnitude := int64(0)
+				args.ScoreMagnitude = &magnitude
+			},
+			wantErr: true,
+		},
+		{
+			name: "score magnitude greater than the max node score",
+			mutate: func(args *PlacementPolicyArgs) {
+				magnitude := int64(101)
+				args.ScoreMagnitude = &magnitude
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := validArgs()
+			tt.mutate(args)
+			if err := ValidatePlacementPolicyArgs(field.NewPath("args"), args); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePlacementPolicyArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
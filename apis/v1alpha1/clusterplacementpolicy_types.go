@@ -0,0 +1,98 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterPlacementPolicySpec defines the desired state of ClusterPlacementPolicy
+type ClusterPlacementPolicySpec struct {
+	// namespaceSelector selects the namespaces of the pods this cluster
+	// placement policy will apply on. A null or empty selector matches all
+	// namespaces.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// The policy weight allows the engine to decide which cluster policy to
+	// use when pods match multiple cluster policies. The cluster policy with
+	// the highest weight is used, and ties are broken as for PlacementPolicy:
+	// Strict over BestEffort, then alphabetically by name. A PlacementPolicy
+	// in the namespace of the pod always takes precedence over cluster
+	// policies. Weight == 0-100 is reserved for future use. Defaults to 101
+	// when not set.
+	Weight int32 `json:"weight,omitempty"`
+	// enforcementMode is an enum that specifies how the policy will be
+	// enforced during scheduler (e.g. the application of filter vs scorer
+	// plugin). Values allowed for this field are:
+	// BestEffort (default): the policy will be enforced as best effort
+	// (scorer mode).
+	// Strict: the policy will be forced during scheduling. The filter
+	// approach will be used. Note: that may yield pods unschedulable.
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+	// podSelector identifies which pods this placement policy will apply on
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// nodeSelector selects the nodes where the placement policy will
+	// apply on according to action. It must not be set when policy.groups
+	// is set.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// Policy is the policy placement for target based on action. The target
+	// is computed separately for each namespace, over the pods matching
+	// podSelector in that namespace.
+	Policy *Policy `json:"policy,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.enforcementMode`
+//+kubebuilder:printcolumn:name="Weight",type=integer,JSONPath=`.spec.weight`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterPlacementPolicy is the Schema for the clusterplacementpolicies API.
+// It applies a placement policy to the pods of all the namespaces matching
+// its namespace selector.
+type ClusterPlacementPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterPlacementPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterPlacementPolicyList contains a list of ClusterPlacementPolicy
+type ClusterPlacementPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterPlacementPolicy `json:"items"`
+}
+
+// PlacementPolicyForNamespace returns the placement policy the cluster placement
+// policy is equivalent to for the pods in the given namespace. The placement policy
+// is not stored, and is controlled by the cluster placement policy.
+func (r *ClusterPlacementPolicy) PlacementPolicyForNamespace(namespace string) *PlacementPolicy {
+	spec := r.Spec.placementPolicySpec()
+	return &PlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.Name,
+			Namespace: namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(r, SchemeGroupVersion.WithKind("ClusterPlacementPolicy")),
+			},
+		},
+		Spec: *spec.DeepCopy(),
+	}
+}
+
+// placementPolicySpec returns the placement policy spec sharing the fields of the
+// cluster placement policy spec. The pods are counted in their own namespace.
+func (s *ClusterPlacementPolicySpec) placementPolicySpec() PlacementPolicySpec {
+	return PlacementPolicySpec{
+		Weight:          s.Weight,
+		EnforcementMode: s.EnforcementMode,
+		PodSelector:     s.PodSelector,
+		NodeSelector:    s.NodeSelector,
+		Policy:          s.Policy,
+	}
+}
@@ -0,0 +1,62 @@
+package v1alpha1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers the ClusterPlacementPolicy webhooks with the manager
+func (r *ClusterPlacementPolicy) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-placement-policy-scheduling-x-k8s-io-v1alpha1-clusterplacementpolicy,mutating=true,failurePolicy=fail,sideEffects=None,groups=placement-policy.scheduling.x-k8s.io,resources=clusterplacementpolicies,verbs=create;update,versions=v1alpha1,name=mclusterplacementpolicy.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &ClusterPlacementPolicy{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+// The defaults are the same as the ones of PlacementPolicy.
+func (r *ClusterPlacementPolicy) Default() {
+	spec := r.Spec.placementPolicySpec()
+	SetDefaultsPlacementPolicySpec(&spec)
+	r.Spec.Weight = spec.Weight
+	r.Spec.Policy = spec.Policy
+}
+
+//+kubebuilder:webhook:path=/validate-placement-policy-scheduling-x-k8s-io-v1alpha1-clusterplacementpolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=placement-policy.scheduling.x-k8s.io,resources=clusterplacementpolicies,verbs=create;update,versions=v1alpha1,name=vclusterplacementpolicy.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &ClusterPlacementPolicy{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ClusterPlacementPolicy) ValidateCreate() error {
+	return r.validateClusterPlacementPolicy()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ClusterPlacementPolicy) ValidateUpdate(old runtime.Object) error {
+	return r.validateClusterPlacementPolicy()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ClusterPlacementPolicy) ValidateDelete() error {
+	return nil
+}
+
+func (r *ClusterPlacementPolicy) validateClusterPlacementPolicy() error {
+	fldPath := field.NewPath("spec")
+	spec := r.Spec.placementPolicySpec()
+	allErrs := ValidatePlacementPolicySpec(&spec, fldPath)
+	if r.Spec.NamespaceSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(r.Spec.NamespaceSelector, fldPath.Child("namespaceSelector"))...)
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(SchemeGroupVersion.WithKind("ClusterPlacementPolicy").GroupKind(), r.Name, allErrs)
+}
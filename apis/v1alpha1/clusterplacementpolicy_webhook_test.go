@@ -0,0 +1,93 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestValidateClusterPlacementPolicy(t *testing.T) {
+	validClusterPlacementPolicy := func() *ClusterPlacementPolicy {
+		targetSize := intstr.FromString("40%")
+		return &ClusterPlacementPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "cpp"},
+			Spec: ClusterPlacementPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"spot-offload": "enabled"}},
+				Weight:            200,
+				EnforcementMode:   EnforcementModeBestEffort,
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+				NodeSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+				Policy:            &Policy{Action: ActionMust, TargetSize: &targetSize},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(cpp *ClusterPlacementPolicy)
+		wantErr bool
+	}{
+		{
+			name:   "valid cluster placement policy",
+			mutate: func(cpp *ClusterPlacementPolicy) {},
+		},
+		{
+			name:   "nil namespace selector",
+			mutate: func(cpp *ClusterPlacementPolicy) { cpp.Spec.NamespaceSelector = nil },
+		},
+		{
+			name: "invalid namespace selector",
+			mutate: func(cpp *ClusterPlacementPolicy) {
+				cpp.Spec.NamespaceSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "spot-offload", Operator: "Invalid"},
+					},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "nil policy",
+			mutate:  func(cpp *ClusterPlacementPolicy) { cpp.Spec.Policy = nil },
+			wantErr: true,
+		},
+		{
+			name:    "weight in reserved range",
+			mutate:  func(cpp *ClusterPlacementPolicy) { cpp.Spec.Weight = 100 },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpp := validClusterPlacementPolicy()
+			tt.mutate(cpp)
+
+			if err := cpp.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := cpp.ValidateUpdate(validClusterPlacementPolicy()); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultClusterPlacementPolicy(t *testing.T) {
+	cpp := &ClusterPlacementPolicy{Spec: ClusterPlacementPolicySpec{Policy: &Policy{}}}
+	cpp.Default()
+
+	if cpp.Spec.Weight != DefaultWeight {
+		t.Errorf("Default() weight = %d, want %d", cpp.Spec.Weight, DefaultWeight)
+	}
+	if cpp.Spec.EnforcementMode != "" {
+		t.Errorf("Default() enforcementMode = %s, want unset", cpp.Spec.EnforcementMode)
+	}
+	if cpp.Spec.Policy.Action != ActionMust {
+		t.Errorf("Default() action = %s, want %s", cpp.Spec.Policy.Action, ActionMust)
+	}
+	if cpp.Spec.Policy.TargetSize == nil || cpp.Spec.Policy.TargetSize.String() != DefaultTargetSize {
+		t.Errorf("Default() targetSize = %v, want %s", cpp.Spec.Policy.TargetSize, DefaultTargetSize)
+	}
+}
@@ -10,6 +10,10 @@ type (
 	EnforcementMode string
 	// Action is an enumeration of the actions
 	Action string
+	// Scope is an enumeration of the sets of pods the target size is computed over
+	Scope string
+	// RoundingMode is an enumeration of the ways a percentage target size is rounded
+	RoundingMode string
 )
 
 const (
@@ -23,10 +27,32 @@ const (
 	// ActionMustNot means the pods must not be placed on the node
 	ActionMustNot Action = "MustNot"
 
+	// ScopeAll means the target size is computed over all the pods matching the pod selector
+	ScopeAll Scope = "All"
+	// ScopePerOwner means the target size is computed separately for the matched pods
+	// of each controlling owner (e.g. ReplicaSet, StatefulSet or Job)
+	ScopePerOwner Scope = "PerOwner"
+
+	// RoundingModeDown means a percentage target size is rounded down to a number of pods
+	RoundingModeDown RoundingMode = "Down"
+	// RoundingModeUp means a percentage target size is rounded up to a number of pods
+	RoundingModeUp RoundingMode = "Up"
+	// RoundingModeNearest means a percentage target size is rounded to the nearest number
+	// of pods, halves being rounded up
+	RoundingModeNearest RoundingMode = "Nearest"
+
 	// PlacementPolicyAnnotationKey is the annotation key for placement policy
 	PlacementPolicyAnnotationKey = "placement-policy.x-k8s.io/policy-name"
 	// PlacementPolicyPreferenceAnnotationKey is the annotation key for placement policy node preference
 	PlacementPolicyPreferenceAnnotationKey = "placement-policy.x-k8s.io/node-preference-matching-labels"
+
+	// PlacementPolicyConditionSatisfied indicates whether the number of pods on nodes
+	// matching the node selector equals the target computed from the policy
+	PlacementPolicyConditionSatisfied = "Satisfied"
+	// PlacementPolicyReasonSatisfied is the condition reason when the placement matches the target
+	PlacementPolicyReasonSatisfied = "Satisfied"
+	// PlacementPolicyReasonDrifted is the condition reason when the placement deviates from the target
+	PlacementPolicyReasonDrifted = "Drifted"
 )
 
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
@@ -34,13 +60,13 @@ const (
 // PlacementPolicySpec defines the desired state of PlacementPolicy
 type PlacementPolicySpec struct {
 	// The policy weight allows the engine to decide which policy to use when
-	// pods match multiple policies. If multiple policies matched and all
-	// share the same weight then a policy with spec.enforcementMode == Force
-	// will be selected. If multiple policies match and +1 policy is marked
-	// as “Force” enforcementMode then they will sorted alphabetically /
-	// ascending and first one will be used. The scheduler publishes events
-	// capturing this conflict when it happens. Weight == 0-100 is reserved
-	// for future use.
+	// pods match multiple policies. The policy with the highest weight is
+	// used. If multiple policies share the highest weight then a policy with
+	// spec.enforcementMode == Strict will be selected over BestEffort ones,
+	// and the remaining ties are broken by sorting the policies
+	// alphabetically / ascending by name and using the first one. The
+	// scheduler publishes events capturing this conflict when it happens.
+	// Weight == 0-100 is reserved for future use. Defaults to 101 when not set.
 	Weight int32 `json:"weight,omitempty"`
 	// enforcementMode is an enum that specifies how the policy will be
 	// enforced during scheduler (e.g. the application of filter vs scorer
@@ -52,8 +78,16 @@ type PlacementPolicySpec struct {
 	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
 	// podSelector identifies which pods this placement policy will apply on
 	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// namespaceSelector is a label query over the set of namespaces whose
+	// pods selected by podSelector are counted when computing targetSize, in
+	// addition to the pods in the namespace of the placement policy, which
+	// are counted even if it doesn't match the selector. A null selector means
+	// only the namespace of the placement policy. An empty selector ({})
+	// matches all namespaces.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
 	// nodeSelector selects the nodes where the placement policy will
-	// apply on according to action
+	// apply on according to action. It must not be set when policy.groups
+	// is set.
 	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
 	// Policy is the policy placement for target based on action
 	Policy *Policy `json:"policy,omitempty"`
@@ -69,18 +103,110 @@ type Policy struct {
 	Action Action `json:"action,omitempty"`
 	// TargetSize is the number of pods that can or cannot be placed on the node.
 	// Value can be an absolute number (ex: 5) or a percentage of desired pods (ex: 10%).
-	// Absolute number is calculated from percentage by rounding down.
+	// Absolute number is calculated from percentage according to rounding,
+	// then bounded by minSize and maxSize.
+	// Defaults to 100% when not set, unless groups is set.
 	TargetSize *intstr.IntOrString `json:"targetSize,omitempty"`
+	// Rounding is how a percentage target size is converted to a number of
+	// pods. It is a string enum that carries the following possible values:
+	// Down(default): the number of pods is rounded down
+	// Up: the number of pods is rounded up
+	// Nearest: the number of pods is rounded to the nearest integer, halves
+	// being rounded up
+	Rounding RoundingMode `json:"rounding,omitempty"`
+	// MinSize is the lower bound of the number of pods computed from
+	// targetSize, so that small workloads still get a minimum number of pods
+	// (e.g. never fewer than 2). It is capped to the number of pods the target
+	// size is computed over.
+	MinSize *int32 `json:"minSize,omitempty"`
+	// MaxSize is the upper bound of the number of pods computed from
+	// targetSize.
+	MaxSize *int32 `json:"maxSize,omitempty"`
+	// Scope is the set of pods the target size is computed over. It is a
+	// string enum that carries the following possible values:
+	// All(default): all the pods matching the pod selector
+	// PerOwner: the matched pods are grouped by their controlling owner
+	// (e.g. ReplicaSet, StatefulSet or Job) and the target size is applied
+	// to each group. Pods without a controlling owner are grouped together.
+	Scope Scope `json:"scope,omitempty"`
+	// TopologyKey is the key of the node labels whose values are the topology
+	// domains (e.g. topology.kubernetes.io/zone) the pods placed on the
+	// preferred group of nodes are spread evenly across. With Strict, the nodes
+	// of the domains with more pods than the least populated domain, and the
+	// nodes without the label, are filtered. With BestEffort, their score is
+	// lowered. When not set, the pods are not spread.
+	TopologyKey string `json:"topologyKey,omitempty"`
+	// Groups split the pods between several groups of nodes, each with its
+	// own node selector and target size (e.g. 50% on spot nodes, 30% on
+	// burstable nodes and 20% on reserved nodes). Each pod is placed on the
+	// group that is the furthest below its target size, the nodes outside of
+	// the groups getting the pods beyond the targets of all the groups. When
+	// set, spec.nodeSelector, targetSize, rounding, minSize and maxSize must
+	// not be set, and action must be Must.
+	Groups []NodeGroup `json:"groups,omitempty"`
+}
+
+// NodeGroup is a group of nodes and the number of pods to place on them
+type NodeGroup struct {
+	// Name identifies the node group in the events of the placement policy
+	Name string `json:"name"`
+	// NodeSelector selects the nodes of the group. The groups are expected
+	// to select disjoint sets of nodes: a node matching the node selectors
+	// of several groups belongs to the first one.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector"`
+	// TargetSize is the number of pods to place on the nodes of the group.
+	// Value can be an absolute number (ex: 5) or a percentage of desired
+	// pods (ex: 10%).
+	TargetSize *intstr.IntOrString `json:"targetSize"`
 }
 
 // PlacementPolicyStatus defines the observed state of PlacementPolicy
 type PlacementPolicyStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the most recent generation observed when
+	// computing the status
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// MatchedPods is the number of pods selected by podSelector
+	MatchedPods int32 `json:"matchedPods"`
+	// PodsOnMatchingNodes is the number of matched pods that are running
+	// on nodes selected by nodeSelector, or by the node selector of any
+	// node group when policy.groups is set
+	PodsOnMatchingNodes int32 `json:"podsOnMatchingNodes"`
+	// TargetPods is the number of matched pods that should be running on
+	// nodes selected by nodeSelector, computed from policy.targetSize and
+	// policy.action, or the sum of the targetPods of the node groups when
+	// policy.groups is set
+	TargetPods int32 `json:"targetPods"`
+	// Groups are the placement of the matched pods on each node group, in
+	// the order of policy.groups
+	Groups []NodeGroupStatus `json:"groups,omitempty"`
+	// Conditions represent the latest available observations of the
+	// placement policy. The Satisfied condition reports whether
+	// podsOnMatchingNodes equals targetPods or, when policy.groups is set,
+	// whether each node group is within one pod of its target.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// NodeGroupStatus is the placement of the pods on a node group
+type NodeGroupStatus struct {
+	// Name is the name of the node group
+	Name string `json:"name"`
+	// PodsOnMatchingNodes is the number of matched pods that are running
+	// on nodes of the node group
+	PodsOnMatchingNodes int32 `json:"podsOnMatchingNodes"`
+	// TargetPods is the number of matched pods that should be running on
+	// nodes of the node group, rounded down as the pods are distributed in
+	// proportion to the target sizes of the node groups
+	TargetPods int32 `json:"targetPods"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.enforcementMode`
+//+kubebuilder:printcolumn:name="Matched",type=integer,JSONPath=`.status.matchedPods`
+//+kubebuilder:printcolumn:name="On-Target",type=integer,JSONPath=`.status.podsOnMatchingNodes`
+//+kubebuilder:printcolumn:name="Target",type=integer,JSONPath=`.status.targetPods`
+//+kubebuilder:printcolumn:name="Satisfied",type=string,JSONPath=`.status.conditions[?(@.type=="Satisfied")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -0,0 +1,293 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+const (
+	// MaxReservedWeight is the upper bound of the weight range reserved for future use
+	MaxReservedWeight int32 = 100
+	// DefaultWeight is the weight assigned to placement policies that don't specify one
+	DefaultWeight int32 = MaxReservedWeight + 1
+	// DefaultEnforcementMode is the enforcement mode of placement policies that don't specify one,
+	// unless the scheduler plugin is configured with another defaultEnforcementMode
+	DefaultEnforcementMode = EnforcementModeBestEffort
+	// DefaultAction is the action assigned to policies that don't specify one
+	DefaultAction = ActionMust
+	// DefaultTargetSize is the target size assigned to policies that don't specify one
+	DefaultTargetSize = "100%"
+	// DefaultScope is the scope assigned to policies that don't specify one
+	DefaultScope = ScopeAll
+	// DefaultRounding is the rounding mode assigned to policies with a target size that don't specify one
+	DefaultRounding = RoundingModeDown
+)
+
+// SetupWebhookWithManager registers the PlacementPolicy webhooks with the manager
+func (r *PlacementPolicy) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-placement-policy-scheduling-x-k8s-io-v1alpha1-placementpolicy,mutating=true,failurePolicy=fail,sideEffects=None,groups=placement-policy.scheduling.x-k8s.io,resources=placementpolicies,verbs=create;update,versions=v1alpha1,name=mplacementpolicy.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &PlacementPolicy{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+// It is also used by the scheduler plugin to compute the effective spec of
+// placement policies that were stored before the webhook was installed.
+func (r *PlacementPolicy) Default() {
+	SetDefaultsPlacementPolicySpec(&r.Spec)
+}
+
+// SetDefaultsPlacementPolicySpec sets the default values of the fields that are not set in the spec.
+// The enforcement mode is left unset, so the defaultEnforcementMode of the scheduler plugin applies.
+func SetDefaultsPlacementPolicySpec(spec *PlacementPolicySpec) {
+	if spec.Weight == 0 {
+		spec.Weight = DefaultWeight
+	}
+	// a missing policy is rejected by the validating webhook rather than defaulted
+	if spec.Policy == nil {
+		return
+	}
+	if spec.Policy.Action == "" {
+		spec.Policy.Action = DefaultAction
+	}
+	// the pods of policies with node groups are placed according to the target sizes of the groups
+	if spec.Policy.TargetSize == nil && len(spec.Policy.Groups) == 0 {
+		targetSize := intstr.FromString(DefaultTargetSize)
+		spec.Policy.TargetSize = &targetSize
+	}
+	if spec.Policy.Rounding == "" && len(spec.Policy.Groups) == 0 {
+		spec.Policy.Rounding = DefaultRounding
+	}
+	if spec.Policy.Scope == "" {
+		spec.Policy.Scope = DefaultScope
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-placement-policy-scheduling-x-k8s-io-v1alpha1-placementpolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=placement-policy.scheduling.x-k8s.io,resources=placementpolicies,verbs=create;update,versions=v1alpha1,name=vplacementpolicy.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &PlacementPolicy{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *PlacementPolicy) ValidateCreate() error {
+	return r.validatePlacementPolicy()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *PlacementPolicy) ValidateUpdate(old runtime.Object) error {
+	return r.validatePlacementPolicy()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *PlacementPolicy) ValidateDelete() error {
+	return nil
+}
+
+func (r *PlacementPolicy) validatePlacementPolicy() error {
+	allErrs := ValidatePlacementPolicySpec(&r.Spec, field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(SchemeGroupVersion.WithKind("PlacementPolicy").GroupKind(), r.Name, allErrs)
+}
+
+// ValidatePlacementPolicySpec validates the placement policy spec and returns the list of errors found
+func ValidatePlacementPolicySpec(spec *PlacementPolicySpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.Weight <= MaxReservedWeight {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("weight"), spec.Weight,
+			fmt.Sprintf("must be greater than %d, weights 0-%d are reserved", MaxReservedWeight, MaxReservedWeight)))
+	}
+
+	switch spec.EnforcementMode {
+	case "", EnforcementModeBestEffort, EnforcementModeStrict:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("enforcementMode"), spec.EnforcementMode,
+			[]string{string(EnforcementModeBestEffort), string(EnforcementModeStrict)}))
+	}
+
+	if spec.PodSelector == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("podSelector"), ""))
+	} else {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(spec.PodSelector, fldPath.Child("podSelector"))...)
+	}
+
+	if spec.NamespaceSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(spec.NamespaceSelector, fldPath.Child("namespaceSelector"))...)
+	}
+
+	// the nodes of policies with node groups are selected by the node selectors of the groups
+	hasGroups := spec.Policy != nil && len(spec.Policy.Groups) > 0
+	switch {
+	case hasGroups && spec.NodeSelector != nil:
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("nodeSelector"), "must not be set when policy.groups is set"))
+	case !hasGroups && spec.NodeSelector == nil:
+		allErrs = append(allErrs, field.Required(fldPath.Child("nodeSelector"), ""))
+	case spec.NodeSelector != nil:
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(spec.NodeSelector, fldPath.Child("nodeSelector"))...)
+	}
+
+	if spec.Policy == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("policy"), ""))
+	} else {
+		allErrs = append(allErrs, validatePolicy(spec.Policy, fldPath.Child("policy"))...)
+	}
+
+	return allErrs
+}
+
+func validatePolicy(policy *Policy, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch policy.Action {
+	case "", ActionMust, ActionMustNot:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("action"), policy.Action,
+			[]string{string(ActionMust), string(ActionMustNot)}))
+	}
+
+	switch policy.Scope {
+	case "", ScopeAll, ScopePerOwner:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("scope"), policy.Scope,
+			[]string{string(ScopeAll), string(ScopePerOwner)}))
+	}
+
+	if policy.TopologyKey != "" {
+		for _, msg := range validation.IsQualifiedName(policy.TopologyKey) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("topologyKey"), policy.TopologyKey, msg))
+		}
+	}
+
+	if len(policy.Groups) > 0 {
+		if policy.Action == ActionMustNot {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("action"), "must be Must when groups is set"))
+		}
+		if policy.TargetSize != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("targetSize"), "must not be set when groups is set"))
+		}
+		if policy.Rounding != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("rounding"), "must not be set when groups is set"))
+		}
+		if policy.MinSize != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("minSize"), "must not be set when groups is set"))
+		}
+		if policy.MaxSize != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("maxSize"), "must not be set when groups is set"))
+		}
+		allErrs = append(allErrs, validateNodeGroups(policy.Groups, fldPath.Child("groups"))...)
+		return allErrs
+	}
+
+	if policy.TargetSize == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("targetSize"), ""))
+	} else {
+		allErrs = append(allErrs, validateTargetSize(policy.TargetSize, fldPath.Child("targetSize"))...)
+	}
+
+	switch policy.Rounding {
+	case "", RoundingModeDown, RoundingModeUp, RoundingModeNearest:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("rounding"), policy.Rounding,
+			[]string{string(RoundingModeDown), string(RoundingModeUp), string(RoundingModeNearest)}))
+	}
+
+	if policy.MinSize != nil && *policy.MinSize < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minSize"), *policy.MinSize, "must be greater than or equal to 0"))
+	}
+	if policy.MaxSize != nil && *policy.MaxSize < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxSize"), *policy.MaxSize, "must be greater than or equal to 0"))
+	}
+	if policy.MinSize != nil && policy.MaxSize != nil && *policy.MinSize > *policy.MaxSize {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxSize"), *policy.MaxSize, "must be greater than or equal to minSize"))
+	}
+
+	return allErrs
+}
+
+// validateNodeGroups validates that the node groups have unique names, node selectors
+// and target sizes, and that the sum of the percentage target sizes doesn't exceed 100%
+func validateNodeGroups(groups []NodeGroup, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	names := make(map[string]bool)
+	percentage := 0
+	for i, group := range groups {
+		idxPath := fldPath.Index(i)
+		if group.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(group.Name) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), group.Name, msg))
+			}
+			if names[group.Name] {
+				allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), group.Name))
+			}
+			names[group.Name] = true
+		}
+
+		if group.NodeSelector == nil {
+			allErrs = append(allErrs, field.Required(idxPath.Child("nodeSelector"), ""))
+		} else {
+			allErrs = append(allErrs, metav1validation.ValidateLabelSelector(group.NodeSelector, idxPath.Child("nodeSelector"))...)
+		}
+
+		if group.TargetSize == nil {
+			allErrs = append(allErrs, field.Required(idxPath.Child("targetSize"), ""))
+			continue
+		}
+		targetSizeErrs := validateTargetSize(group.TargetSize, idxPath.Child("targetSize"))
+		allErrs = append(allErrs, targetSizeErrs...)
+		if len(targetSizeErrs) == 0 && group.TargetSize.Type == intstr.String {
+			v, _ := strconv.Atoi(strings.TrimSuffix(group.TargetSize.StrVal, "%"))
+			percentage += v
+		}
+	}
+	if percentage > 100 {
+		allErrs = append(allErrs, field.Invalid(fldPath, fmt.Sprintf("%d%%", percentage), "the sum of the percentage target sizes must not exceed 100%"))
+	}
+
+	return allErrs
+}
+
+// validateTargetSize validates that the target size is a non-negative number
+// or a percentage between 0% and 100%
+func validateTargetSize(targetSize *intstr.IntOrString, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch targetSize.Type {
+	case intstr.Int:
+		if targetSize.IntVal < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath, targetSize.IntVal, "must be greater than or equal to 0"))
+		}
+	case intstr.String:
+		if !strings.HasSuffix(targetSize.StrVal, "%") {
+			allErrs = append(allErrs, field.Invalid(fldPath, targetSize.StrVal, "must be an integer or a percentage (e.g. 40%)"))
+			break
+		}
+		v, err := strconv.Atoi(strings.TrimSuffix(targetSize.StrVal, "%"))
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath, targetSize.StrVal, "must be an integer or a percentage (e.g. 40%)"))
+			break
+		}
+		if v < 0 || v > 100 {
+			allErrs = append(allErrs, field.Invalid(fldPath, targetSize.StrVal, "must be a percentage between 0% and 100%"))
+		}
+	}
+
+	return allErrs
+}
@@ -0,0 +1,367 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestValidatePlacementPolicy(t *testing.T) {
+	validPlacementPolicy := func() *PlacementPolicy {
+		targetSize := intstr.FromString("40%")
+		return &PlacementPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "default"},
+			Spec: PlacementPolicySpec{
+				Weight:          200,
+				EnforcementMode: EnforcementModeBestEffort,
+				PodSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+				NodeSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+				Policy:          &Policy{Action: ActionMust, TargetSize: &targetSize},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(pp *PlacementPolicy)
+		wantErr bool
+	}{
+		{
+			name:   "valid placement policy",
+			mutate: func(pp *PlacementPolicy) {},
+		},
+		{
+			name: "valid placement policy with match expressions",
+			mutate: func(pp *PlacementPolicy) {
+				pp.Spec.NodeSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "kubernetes.azure.com/scalesetpriority", Operator: metav1.LabelSelectorOpExists},
+					},
+				}
+			},
+		},
+		{
+			name: "valid placement policy with absolute target size",
+			mutate: func(pp *PlacementPolicy) {
+				targetSize := intstr.FromInt(5)
+				pp.Spec.Policy.TargetSize = &targetSize
+			},
+		},
+		{
+			name: "valid placement policy with namespace selector",
+			mutate: func(pp *PlacementPolicy) {
+				pp.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+			},
+		},
+		{
+			name: "invalid namespace selector",
+			mutate: func(pp *PlacementPolicy) {
+				pp.Spec.NamespaceSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "team", Operator: metav1.LabelSelectorOpIn},
+					},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "nil pod selector",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.PodSelector = nil },
+			wantErr: true,
+		},
+		{
+			name:    "nil node selector",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.NodeSelector = nil },
+			wantErr: true,
+		},
+		{
+			name:    "nil policy",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.Policy = nil },
+			wantErr: true,
+		},
+		{
+			name:    "nil target size",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.Policy.TargetSize = nil },
+			wantErr: true,
+		},
+		{
+			name: "invalid match expression operator",
+			mutate: func(pp *PlacementPolicy) {
+				pp.Spec.PodSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "app", Operator: "Invalid"},
+					},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown enforcement mode",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.EnforcementMode = "Force" },
+			wantErr: true,
+		},
+		{
+			name:   "valid placement policy with per owner scope",
+			mutate: func(pp *PlacementPolicy) { pp.Spec.Policy.Scope = ScopePerOwner },
+		},
+		{
+			name:    "unknown scope",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.Policy.Scope = "PerNode" },
+			wantErr: true,
+		},
+		{
+			name:   "valid placement policy with topology key",
+			mutate: func(pp *PlacementPolicy) { pp.Spec.Policy.TopologyKey = "topology.kubernetes.io/zone" },
+		},
+		{
+			name:    "invalid topology key",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.Policy.TopologyKey = "topology/kubernetes/zone" },
+			wantErr: true,
+		},
+		{
+			name: "valid placement policy with rounding and bounds",
+			mutate: func(pp *PlacementPolicy) {
+				pp.Spec.Policy.Rounding = RoundingModeNearest
+				pp.Spec.Policy.MinSize = int32Ptr(2)
+				pp.Spec.Policy.MaxSize = int32Ptr(5)
+			},
+		},
+		{
+			name:    "unknown rounding mode",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.Policy.Rounding = "Half" },
+			wantErr: true,
+		},
+		{
+			name:    "negative min size",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.Policy.MinSize = int32Ptr(-1) },
+			wantErr: true,
+		},
+		{
+			name:    "negative max size",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.Policy.MaxSize = int32Ptr(-1) },
+			wantErr: true,
+		},
+		{
+			name: "min size greater than max size",
+			mutate: func(pp *PlacementPolicy) {
+				pp.Spec.Policy.MinSize = int32Ptr(3)
+				pp.Spec.Policy.MaxSize = int32Ptr(2)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown action",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.Policy.Action = "Should" },
+			wantErr: true,
+		},
+		{
+			name: "negative target size",
+			mutate: func(pp *PlacementPolicy) {
+				targetSize := intstr.FromInt(-1)
+				pp.Spec.Policy.TargetSize = &targetSize
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative target size percentage",
+			mutate: func(pp *PlacementPolicy) {
+				targetSize := intstr.FromString("-10%")
+				pp.Spec.Policy.TargetSize = &targetSize
+			},
+			wantErr: true,
+		},
+		{
+			name: "target size percentage greater than 100%",
+			mutate: func(pp *PlacementPolicy) {
+				targetSize := intstr.FromString("101%")
+				pp.Spec.Policy.TargetSize = &targetSize
+			},
+			wantErr: true,
+		},
+		{
+			name: "target size is not a percentage",
+			mutate: func(pp *PlacementPolicy) {
+				targetSize := intstr.FromString("forty")
+				pp.Spec.Policy.TargetSize = &targetSize
+			},
+			wantErr: true,
+		},
+		{
+			name:   "valid placement policy with node groups",
+			mutate: func(pp *PlacementPolicy) { setTestNodeGroups(pp, "50%", "30%", "20%") },
+		},
+		{
+			name: "node groups with node selector",
+			mutate: func(pp *PlacementPolicy) {
+				nodeSelector := pp.Spec.NodeSelector
+				setTestNodeGroups(pp, "50%", "50%")
+				pp.Spec.NodeSelector = nodeSelector
+			},
+			wantErr: true,
+		},
+		{
+			name: "node groups with target size",
+			mutate: func(pp *PlacementPolicy) {
+				targetSize := pp.Spec.Policy.TargetSize
+				setTestNodeGroups(pp, "50%", "50%")
+				pp.Spec.Policy.TargetSize = targetSize
+			},
+			wantErr: true,
+		},
+		{
+			name: "node groups with must not action",
+			mutate: func(pp *PlacementPolicy) {
+				setTestNodeGroups(pp, "50%", "50%")
+				pp.Spec.Policy.Action = ActionMustNot
+			},
+			wantErr: true,
+		},
+		{
+			name: "node groups with min size",
+			mutate: func(pp *PlacementPolicy) {
+				setTestNodeGroups(pp, "50%", "50%")
+				pp.Spec.Policy.MinSize = int32Ptr(2)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "node groups percentages greater than 100%",
+			mutate:  func(pp *PlacementPolicy) { setTestNodeGroups(pp, "60%", "50%") },
+			wantErr: true,
+		},
+		{
+			name: "node groups with duplicate names",
+			mutate: func(pp *PlacementPolicy) {
+				setTestNodeGroups(pp, "50%", "50%")
+				pp.Spec.Policy.Groups[1].Name = pp.Spec.Policy.Groups[0].Name
+			},
+			wantErr: true,
+		},
+		{
+			name: "node group without node selector",
+			mutate: func(pp *PlacementPolicy) {
+				setTestNodeGroups(pp, "50%", "50%")
+				pp.Spec.Policy.Groups[1].NodeSelector = nil
+			},
+			wantErr: true,
+		},
+		{
+			name: "node group without target size",
+			mutate: func(pp *PlacementPolicy) {
+				setTestNodeGroups(pp, "50%", "50%")
+				pp.Spec.Policy.Groups[1].TargetSize = nil
+			},
+			wantErr: true,
+		},
+		{
+			name:    "weight in reserved range",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.Weight = 100 },
+			wantErr: true,
+		},
+		{
+			name:    "zero weight",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.Weight = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "negative weight",
+			mutate:  func(pp *PlacementPolicy) { pp.Spec.Weight = -1 },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp := validPlacementPolicy()
+			tt.mutate(pp)
+
+			if err := pp.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := pp.ValidateUpdate(validPlacementPolicy()); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultPlacementPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		spec PlacementPolicySpec
+		want PlacementPolicySpec
+	}{
+		{
+			name: "empty policy",
+			spec: PlacementPolicySpec{Policy: &Policy{}},
+			want: PlacementPolicySpec{
+				Weight: DefaultWeight,
+				Policy: &Policy{Action: ActionMust, TargetSize: intOrStringPtr(intstr.FromString("100%")), Rounding: RoundingModeDown, Scope: ScopeAll},
+			},
+		},
+		{
+			name: "nil policy is not defaulted",
+			spec: PlacementPolicySpec{},
+			want: PlacementPolicySpec{
+				Weight: DefaultWeight,
+			},
+		},
+		{
+			name: "target size of policy with node groups is not defaulted",
+			spec: PlacementPolicySpec{Policy: &Policy{Groups: []NodeGroup{{Name: "spot"}}}},
+			want: PlacementPolicySpec{
+				Weight: DefaultWeight,
+				Policy: &Policy{Action: ActionMust, Scope: ScopeAll, Groups: []NodeGroup{{Name: "spot"}}},
+			},
+		},
+		{
+			name: "set fields are preserved",
+			spec: PlacementPolicySpec{
+				Weight:          200,
+				EnforcementMode: EnforcementModeStrict,
+				Policy:          &Policy{Action: ActionMustNot, TargetSize: intOrStringPtr(intstr.FromInt(3)), Rounding: RoundingModeUp, Scope: ScopePerOwner},
+			},
+			want: PlacementPolicySpec{
+				Weight:          200,
+				EnforcementMode: EnforcementModeStrict,
+				Policy:          &Policy{Action: ActionMustNot, TargetSize: intOrStringPtr(intstr.FromInt(3)), Rounding: RoundingModeUp, Scope: ScopePerOwner},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp := &PlacementPolicy{Spec: tt.spec}
+			pp.Default()
+			if !reflect.DeepEqual(pp.Spec, tt.want) {
+				t.Errorf("Default() = %+v, want %+v", pp.Spec, tt.want)
+			}
+		})
+	}
+}
+
+// setTestNodeGroups replaces the node selector and the target size of the placement policy
+// with node groups of the given target sizes
+func setTestNodeGroups(pp *PlacementPolicy, targetSizes ...string) {
+	pp.Spec.NodeSelector = nil
+	pp.Spec.Policy.TargetSize = nil
+	pp.Spec.Policy.Groups = nil
+	for i, targetSize := range targetSizes {
+		pp.Spec.Policy.Groups = append(pp.Spec.Policy.Groups, NodeGroup{
+			Name:         fmt.Sprintf("group%d", i),
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"group": fmt.Sprintf("group%d", i)}},
+			TargetSize:   intOrStringPtr(intstr.FromString(targetSize)),
+		})
+	}
+}
+
+func intOrStringPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
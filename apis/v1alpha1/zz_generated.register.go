@@ -42,6 +42,8 @@ func init() {
 // Adds the list of known types to Scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&ClusterPlacementPolicy{},
+		&ClusterPlacementPolicyList{},
 		&PlacementPolicy{},
 		&PlacementPolicyList{},
 	)
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	ppclientset "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/clientset/versioned"
+	ppinformers "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/informers/externalversions"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/controller/status"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/metrics"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+	"k8s.io/klog/v2/klogr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	// +kubebuilder:scaffold:imports
+)
+
+var (
+	scheme = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	// +kubebuilder:scaffold:scheme
+}
+
+func main() {
+	var (
+		metricsAddr          string
+		probeAddr            string
+		webhookPort          int
+		certDir              string
+		enableLeaderElection bool
+		enableWebhooks       bool
+		defaultMode          string
+	)
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the webhook server serves at.")
+	flag.StringVar(&certDir, "cert-dir", "/tmp/k8s-webhook-server/serving-certs", "The directory that contains the webhook server key and certificate.")
+	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
+		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", true, "Serve the admission webhooks of the placement policies.")
+	flag.StringVar(&defaultMode, "default-enforcement-mode", string(v1alpha1.DefaultEnforcementMode), "The defaultEnforcementMode argument of the scheduler plugin.")
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	defaultEnforcementMode := v1alpha1.EnforcementMode(defaultMode)
+	if defaultEnforcementMode != v1alpha1.EnforcementModeBestEffort && defaultEnforcementMode != v1alpha1.EnforcementModeStrict {
+		klog.ErrorS(nil, "default-enforcement-mode must be BestEffort or Strict", "defaultEnforcementMode", defaultMode)
+		os.Exit(1)
+	}
+
+	ctrl.SetLogger(klogr.New())
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:                 scheme,
+		MetricsBindAddress:     metricsAddr,
+		HealthProbeBindAddress: probeAddr,
+		Port:                   webhookPort,
+		CertDir:                certDir,
+		LeaderElection:         enableLeaderElection,
+		LeaderElectionID:       "placement-policy-controller-manager",
+	})
+	if err != nil {
+		klog.ErrorS(err, "unable to create manager")
+		os.Exit(1)
+	}
+
+	readyCheck := healthz.Ping
+	if enableWebhooks {
+		if err = (&v1alpha1.ClusterPlacementPolicy{}).SetupWebhookWithManager(mgr); err != nil {
+			klog.ErrorS(err, "unable to create webhook", "webhook", "ClusterPlacementPolicy")
+			os.Exit(1)
+		}
+		if err = (&v1alpha1.PlacementPolicy{}).SetupWebhookWithManager(mgr); err != nil {
+			klog.ErrorS(err, "unable to create webhook", "webhook", "PlacementPolicy")
+			os.Exit(1)
+		}
+		readyCheck = mgr.GetWebhookServer().StartedChecker()
+	}
+	// +kubebuilder:scaffold:builder
+
+	if err = addStatusController(mgr, defaultEnforcementMode); err != nil {
+		klog.ErrorS(err, "unable to create controller", "controller", "PlacementPolicyStatus")
+		os.Exit(1)
+	}
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		klog.ErrorS(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("readyz", readyCheck); err != nil {
+		klog.ErrorS(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+
+	klog.InfoS("starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		klog.ErrorS(err, "unable to run manager")
+		os.Exit(1)
+	}
+}
+
+// addStatusController adds the placement policy status controller to the manager. The controller
+// only runs on the leader when leader election is enabled, so a single instance writes the status.
+// defaultEnforcementMode is the defaultEnforcementMode argument of the scheduler plugin.
+func addStatusController(mgr manager.Manager, defaultEnforcementMode v1alpha1.EnforcementMode) error {
+	client, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	ppClient, err := ppclientset.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+
+	// the status gauges are served next to the controller-runtime metrics
+	metrics.RegisterPolicyStatus()
+	if err := mgr.AddMetricsExtraHandler("/metrics/placement-policy", legacyregistry.Handler()); err != nil {
+		return err
+	}
+
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	namespaceInformer := informerFactory.Core().V1().Namespaces()
+	ppInformerFactory := ppinformers.NewSharedInformerFactory(ppClient, 0)
+	ppInformer := ppInformerFactory.Placementpolicy().V1alpha1().PlacementPolicies()
+	cppInformer := ppInformerFactory.Placementpolicy().V1alpha1().ClusterPlacementPolicies()
+
+	ppMgr := core.NewPlacementPolicyManager(
+		client,
+		ppClient,
+		nil,
+		ppInformer,
+		cppInformer,
+		podInformer.Lister(),
+		namespaceInformer.Lister(),
+		defaultEnforcementMode)
+
+	controller := status.NewController(ppClient, ppInformer, podInformer, nodeInformer, namespaceInformer, ppMgr)
+
+	// a runnable that doesn't implement LeaderElectionRunnable only runs on the leader
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		informerFactory.Start(ctx.Done())
+		ppInformerFactory.Start(ctx.Done())
+		informerFactory.WaitForCacheSync(ctx.Done())
+		ppInformerFactory.WaitForCacheSync(ctx.Done())
+		controller.Run(ctx, 1)
+		return nil
+	}))
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// explanation is the placement decision of the scheduler plugin for a pod
+type explanation struct {
+	pod *corev1.Pod
+	// node is the node the pod is scheduled on, nil if the pod is not scheduled
+	node *corev1.Node
+	// policies are the placement policies matching the pod, in the order of precedence
+	policies []*v1alpha1.PlacementPolicy
+	// nodeMatches is true if the node of the pod matches the node selector of the
+	// applied placement policy
+	nodeMatches bool
+	// podsInScope is the number of pods the target size of the applied placement
+	// policy is computed over
+	podsInScope int
+	// podsOnMatchingNodes is the number of pods in scope on the nodes matching the
+	// node selector of the applied placement policy
+	podsOnMatchingNodes int
+	// targetSize is the number of pods in scope that should be on the nodes matching
+	// the node selector of the applied placement policy
+	targetSize int
+	// nodeGroup is the node group of the applied placement policy the node of the pod
+	// belongs to, if the placement policy has node groups
+	nodeGroup string
+	// podsInGroup is the number of pods in scope on the nodes of each node group of the
+	// applied placement policy, followed by the number of pods on the other nodes
+	podsInGroup []int
+}
+
+// explain looks up the placement policies of the pod with the same logic as the scheduler
+// plugin, and computes the current placement of the pods counted by the applied policy.
+func explain(ctx context.Context, ppMgr core.Manager, nodeLister corelisters.NodeLister, pod *corev1.Pod) (*explanation, error) {
+	e := &explanation{pod: pod}
+	if pod.Spec.NodeName != "" {
+		node, err := nodeLister.Get(pod.Spec.NodeName)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		e.node = node
+	}
+
+	policies, err := ppMgr.GetPlacementPoliciesForPod(ctx, pod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get placement policies for pod: %w", err)
+	}
+	e.policies = policies
+	if len(policies) == 0 {
+		return e, nil
+	}
+	pp := policies[0]
+	if len(pp.Spec.Policy.Groups) > 0 {
+		return e, e.explainNodeGroups(ctx, ppMgr, nodeLister, pp)
+	}
+
+	nodeSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.NodeSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse node selector: %w", err)
+	}
+	if e.node != nil {
+		e.nodeMatches = nodeSelector.Matches(labels.Set(e.node.Labels))
+	}
+
+	podList, err := ppMgr.GetPodsForPlacementPolicy(ctx, pp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods for placement policy: %w", err)
+	}
+	for _, p := range core.GetPodsInScope(pp, podList, pod) {
+		e.podsInScope++
+		if p.Spec.NodeName == "" {
+			continue
+		}
+		node, err := nodeLister.Get(p.Spec.NodeName)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if nodeSelector.Matches(labels.Set(node.Labels)) {
+			e.podsOnMatchingNodes++
+		}
+	}
+	if e.targetSize, err = core.GetTargetSize(pp, e.podsInScope); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// explainNodeGroups computes the current placement of the pods counted by a placement policy
+// with node groups
+func (e *explanation) explainNodeGroups(ctx context.Context, ppMgr core.Manager, nodeLister corelisters.NodeLister, pp *v1alpha1.PlacementPolicy) error {
+	groupSelectors, err := core.GetNodeGroupSelectors(pp)
+	if err != nil {
+		return err
+	}
+	if e.node != nil {
+		e.nodeGroup = nodeGroupName(pp, core.GetNodeGroup(e.node, groupSelectors))
+	}
+
+	podList, err := ppMgr.GetPodsForPlacementPolicy(ctx, pp)
+	if err != nil {
+		return fmt.Errorf("failed to get pods for placement policy: %w", err)
+	}
+	e.podsInGroup = make([]int, len(groupSelectors)+1)
+	for _, p := range core.GetPodsInScope(pp, podList, e.pod) {
+		e.podsInScope++
+		if p.Spec.NodeName == "" {
+			continue
+		}
+		node, err := nodeLister.Get(p.Spec.NodeName)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		group := core.GetNodeGroup(node, groupSelectors)
+		if group < 0 {
+			group = len(groupSelectors)
+		}
+		e.podsInGroup[group]++
+	}
+	return nil
+}
+
+// print writes the explanation in a human readable format
+func (e *explanation) print(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Pod:\t%s/%s\n", e.pod.Namespace, e.pod.Name)
+	switch {
+	case e.pod.Spec.NodeName == "":
+		fmt.Fprintf(w, "Node:\t<none>\n")
+	case len(e.policies) == 0:
+		fmt.Fprintf(w, "Node:\t%s\n", e.pod.Spec.NodeName)
+	case e.podsInGroup != nil:
+		fmt.Fprintf(w, "Node:\t%s (node group: %s)\n", e.pod.Spec.NodeName, e.nodeGroup)
+	default:
+		fmt.Fprintf(w, "Node:\t%s (matches the node selector: %t)\n", e.pod.Spec.NodeName, e.nodeMatches)
+	}
+	fmt.Fprintf(w, "Annotations:\t%s=%s\n", v1alpha1.PlacementPolicyAnnotationKey, annotation(e.pod, v1alpha1.PlacementPolicyAnnotationKey))
+	fmt.Fprintf(w, "\t%s=%s\n", v1alpha1.PlacementPolicyPreferenceAnnotationKey, annotation(e.pod, v1alpha1.PlacementPolicyPreferenceAnnotationKey))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(e.policies) == 0 {
+		_, err := fmt.Fprintf(out, "\nNo placement policy matches the pod.\n")
+		return err
+	}
+
+	fmt.Fprintf(out, "\nMatching placement policies, in the order of precedence:\n")
+	w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "  \tKIND\tNAME\tWEIGHT\tMODE\tACTION\tTARGET SIZE\tSCOPE\n")
+	for i, pp := range e.policies {
+		applied := ""
+		if i == 0 {
+			applied = "*"
+		}
+		kind, name := "PlacementPolicy", pp.Namespace+"/"+pp.Name
+		if owner := metav1.GetControllerOf(pp); owner != nil {
+			kind, name = owner.Kind, owner.Name
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", applied, kind, name, pp.Spec.Weight, pp.Spec.EnforcementMode,
+			pp.Spec.Policy.Action, targetSize(pp), pp.Spec.Policy.Scope)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	pp := e.policies[0]
+	fmt.Fprintf(out, "\nPlacement of the pods counted by placement policy %s:\n", pp.Name)
+	w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "  Pods:\t%d\n", e.podsInScope)
+	if e.podsInGroup != nil {
+		for i, group := range pp.Spec.Policy.Groups {
+			fmt.Fprintf(w, "  On node group %s:\t%d (target size: %s)\n", group.Name, e.podsInGroup[i], group.TargetSize.String())
+		}
+		fmt.Fprintf(w, "  On other nodes:\t%d\n", e.podsInGroup[len(pp.Spec.Policy.Groups)])
+		return w.Flush()
+	}
+	fmt.Fprintf(w, "  On matching nodes:\t%d\n", e.podsOnMatchingNodes)
+	fmt.Fprintf(w, "  Target on matching nodes:\t%d\n", e.targetSize)
+	return w.Flush()
+}
+
+// targetSize returns the target size of the placement policy with its bounds, or the target sizes
+// of its node groups
+func targetSize(pp *v1alpha1.PlacementPolicy) string {
+	if len(pp.Spec.Policy.Groups) == 0 {
+		var bounds []string
+		if pp.Spec.Policy.MinSize != nil {
+			bounds = append(bounds, fmt.Sprintf("min: %d", *pp.Spec.Policy.MinSize))
+		}
+		if pp.Spec.Policy.MaxSize != nil {
+			bounds = append(bounds, fmt.Sprintf("max: %d", *pp.Spec.Policy.MaxSize))
+		}
+		if len(bounds) == 0 {
+			return pp.Spec.Policy.TargetSize.String()
+		}
+		return fmt.Sprintf("%s (%s)", pp.Spec.Policy.TargetSize.String(), strings.Join(bounds, ", "))
+	}
+	targetSizes := make([]string, 0, len(pp.Spec.Policy.Groups))
+	for _, group := range pp.Spec.Policy.Groups {
+		targetSizes = append(targetSizes, group.Name+"="+group.TargetSize.String())
+	}
+	return strings.Join(targetSizes, ",")
+}
+
+// nodeGroupName returns the name of the node group at the index, or <none> for the nodes
+// outside of the node groups
+func nodeGroupName(pp *v1alpha1.PlacementPolicy, group int) string {
+	if group < 0 || group >= len(pp.Spec.Policy.Groups) {
+		return "<none>"
+	}
+	return pp.Spec.Policy.Groups[group].Name
+}
+
+func annotation(pod *corev1.Pod, key string) string {
+	if value, ok := pod.Annotations[key]; ok {
+		return value
+	}
+	return "<none>"
+}
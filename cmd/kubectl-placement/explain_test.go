@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	ppfake "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/clientset/versioned/fake"
+	ppinformers "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/informers/externalversions"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+func TestExplain(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"node": "want"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"node": "unwant"}}},
+	}
+	makePod := func(name, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "nginx"}},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	pods := []*corev1.Pod{
+		makePod("pod1", "node1"),
+		makePod("pod2", "node2"),
+		makePod("pod3", "node2"),
+		makePod("pod4", ""),
+	}
+	pods[0].Annotations = map[string]string{
+		v1alpha1.PlacementPolicyAnnotationKey:           "pp-high",
+		v1alpha1.PlacementPolicyPreferenceAnnotationKey: "true",
+	}
+	makePP := func(name string, weight int32, targetSize intstr.IntOrString) *v1alpha1.PlacementPolicy {
+		return &v1alpha1.PlacementPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1alpha1.PlacementPolicySpec{
+				Weight:       weight,
+				PodSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+				NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+				Policy:       &v1alpha1.Policy{TargetSize: &targetSize},
+			},
+		}
+	}
+	ppList := []*v1alpha1.PlacementPolicy{
+		makePP("pp-low", 150, intstr.FromInt(1)),
+		makePP("pp-high", 200, intstr.FromString("50%")),
+	}
+	ppMgr, nodeLister := newTestPlacementPolicyManager(t, nodes, pods, ppList)
+
+	e, err := explain(context.Background(), ppMgr, nodeLister, pods[0])
+	if err != nil {
+		t.Fatalf("explain() error = %v", err)
+	}
+	if len(e.policies) != 2 || e.policies[0].Name != "pp-high" || e.policies[1].Name != "pp-low" {
+		t.Errorf("explain() policies = %v, want [pp-high pp-low]", e.policies)
+	}
+	if !e.nodeMatches {
+		t.Errorf("explain() nodeMatches = false, want true")
+	}
+	if e.podsInScope != 4 || e.podsOnMatchingNodes != 1 || e.targetSize != 2 {
+		t.Errorf("explain() pods = %d, on matching nodes = %d, target = %d, want 4, 1, 2", e.podsInScope, e.podsOnMatchingNodes, e.targetSize)
+	}
+
+	var out bytes.Buffer
+	if err := e.print(&out); err != nil {
+		t.Fatalf("print() error = %v", err)
+	}
+	for _, want := range []string{
+		"Node:         node1 (matches the node selector: true)",
+		"placement-policy.x-k8s.io/policy-name=pp-high",
+		"placement-policy.x-k8s.io/node-preference-matching-labels=true",
+		"*  PlacementPolicy  default/pp-high  200",
+		"PlacementPolicy  default/pp-low   150",
+		"On matching nodes:         1",
+		"Target on matching nodes:  2",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("print() output doesn't contain %q:\n%s", want, out.String())
+		}
+	}
+
+	e, err = explain(context.Background(), ppMgr, nodeLister, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}})
+	if err != nil {
+		t.Fatalf("explain() error = %v", err)
+	}
+	out.Reset()
+	if err := e.print(&out); err != nil {
+		t.Fatalf("print() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "No placement policy matches the pod.") {
+		t.Errorf("print() output doesn't report that no placement policy matches:\n%s", out.String())
+	}
+}
+
+func newTestPlacementPolicyManager(t *testing.T, nodes []*corev1.Node, pods []*corev1.Pod, ppList []*v1alpha1.PlacementPolicy) (core.Manager, corelisters.NodeLister) {
+	t.Helper()
+	client := fake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	ppClient := ppfake.NewSimpleClientset()
+	ppInformerFactory := ppinformers.NewSharedInformerFactory(ppClient, 0)
+	ppInformer := ppInformerFactory.Placementpolicy().V1alpha1().PlacementPolicies()
+	cppInformer := ppInformerFactory.Placementpolicy().V1alpha1().ClusterPlacementPolicies()
+	podInformer := informerFactory.Core().V1().Pods()
+	nodeInformer := informerFactory.Core().V1().Nodes()
+
+	for _, node := range nodes {
+		if err := nodeInformer.Informer().GetIndexer().Add(node); err != nil {
+			t.Fatalf("failed to add node: %v", err)
+		}
+	}
+	for _, pod := range pods {
+		if err := podInformer.Informer().GetIndexer().Add(pod); err != nil {
+			t.Fatalf("failed to add pod: %v", err)
+		}
+	}
+	for _, pp := range ppList {
+		if err := ppInformer.Informer().GetIndexer().Add(pp); err != nil {
+			t.Fatalf("failed to add placement policy: %v", err)
+		}
+	}
+
+	ppMgr := core.NewPlacementPolicyManager(client, ppClient, nil, ppInformer, cppInformer,
+		podInformer.Lister(), informerFactory.Core().V1().Namespaces().Lister(), v1alpha1.DefaultEnforcementMode)
+	return ppMgr, nodeInformer.Lister()
+}
+
+func TestExplainNodeGroups(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"tier": "spot"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"tier": "ondemand"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"tier": "reserved"}}},
+	}
+	makePod := func(name, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "nginx"}},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	pods := []*corev1.Pod{
+		makePod("pod1", "node1"),
+		makePod("pod2", "node1"),
+		makePod("pod3", "node2"),
+		makePod("pod4", "node3"),
+	}
+	makeGroup := func(name string, targetSize intstr.IntOrString) v1alpha1.NodeGroup {
+		return v1alpha1.NodeGroup{
+			Name:         name,
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": name}},
+			TargetSize:   &targetSize,
+		}
+	}
+	pp := &v1alpha1.PlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "default"},
+		Spec: v1alpha1.PlacementPolicySpec{
+			PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+			Policy: &v1alpha1.Policy{Groups: []v1alpha1.NodeGroup{
+				makeGroup("spot", intstr.FromString("50%")),
+				makeGroup("ondemand", intstr.FromString("50%")),
+			}},
+		},
+	}
+	ppMgr, nodeLister := newTestPlacementPolicyManager(t, nodes, pods, []*v1alpha1.PlacementPolicy{pp})
+
+	e, err := explain(context.Background(), ppMgr, nodeLister, pods[0])
+	if err != nil {
+		t.Fatalf("explain() error = %v", err)
+	}
+	if e.nodeGroup != "spot" || !reflect.DeepEqual(e.podsInGroup, []int{2, 1, 1}) {
+		t.Errorf("explain() node group = %s, pods in group = %v, want spot, [2 1 1]", e.nodeGroup, e.podsInGroup)
+	}
+
+	var out bytes.Buffer
+	if err := e.print(&out); err != nil {
+		t.Fatalf("print() error = %v", err)
+	}
+	for _, want := range []string{
+		"Node:         node1 (node group: spot)",
+		"spot=50%,ondemand=50%",
+		"On node group spot:      2 (target size: 50%)",
+		"On other nodes:          1",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("print() output doesn't contain %q:\n%s", want, out.String())
+		}
+	}
+}
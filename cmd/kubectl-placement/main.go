@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	ppclientset "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/clientset/versioned"
+	ppinformers "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/informers/externalversions"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const usage = `Explain the placement decision of the placement policy scheduler plugin for a pod.
+
+Usage:
+  kubectl placement [flags] POD
+
+Flags:
+`
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		kubeconfig  string
+		kubecontext string
+		namespace   string
+		timeout     time.Duration
+		defaultMode string
+	)
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file.")
+	flag.StringVar(&kubecontext, "context", "", "The name of the kubeconfig context to use.")
+	flag.StringVar(&namespace, "namespace", "", "The namespace of the pod. Defaults to the namespace of the kubeconfig context.")
+	flag.StringVar(&namespace, "n", "", "Shorthand for --namespace.")
+	flag.StringVar(&defaultMode, "default-enforcement-mode", string(v1alpha1.DefaultEnforcementMode), "The defaultEnforcementMode argument of the scheduler plugin.")
+	flag.DurationVar(&timeout, "request-timeout", time.Minute, "The time to wait for the objects to be listed.")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		return fmt.Errorf("expected exactly one pod name, got %d arguments", flag.NArg())
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: kubecontext})
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	if namespace == "" {
+		if namespace, _, err = clientConfig.Namespace(); err != nil {
+			return err
+		}
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	ppClient, err := ppclientset.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, flag.Arg(0), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	// the policies are looked up with the placement policy manager of the scheduler
+	// plugin, so the explanation matches the decision of the scheduler
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	namespaceInformer := informerFactory.Core().V1().Namespaces()
+	ppInformerFactory := ppinformers.NewSharedInformerFactory(ppClient, 0)
+	ppInformer := ppInformerFactory.Placementpolicy().V1alpha1().PlacementPolicies()
+	cppInformer := ppInformerFactory.Placementpolicy().V1alpha1().ClusterPlacementPolicies()
+	ppMgr := core.NewPlacementPolicyManager(
+		client,
+		ppClient,
+		nil,
+		ppInformer,
+		cppInformer,
+		podInformer.Lister(),
+		namespaceInformer.Lister(),
+		v1alpha1.EnforcementMode(defaultMode))
+
+	informerFactory.Start(ctx.Done())
+	ppInformerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(),
+		podInformer.Informer().HasSynced,
+		nodeInformer.Informer().HasSynced,
+		namespaceInformer.Informer().HasSynced,
+		ppInformer.Informer().HasSynced,
+		cppInformer.Informer().HasSynced) {
+		return fmt.Errorf("timed out waiting for the objects to be listed")
+	}
+
+	e, err := explain(ctx, ppMgr, nodeInformer.Lister(), pod)
+	if err != nil {
+		return err
+	}
+	return e.print(os.Stdout)
+}
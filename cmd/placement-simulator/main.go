@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/simulator"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const usage = `Simulate the placement of the replicas of a pod by the placement policy scheduler plugin.
+
+The nodes, pods, namespaces and placement policies are read from YAML or JSON files, such as:
+  kubectl get nodes,namespaces,pods,placementpolicies,clusterplacementpolicies -A -o yaml > cluster.yaml
+
+Usage:
+  placement-simulator --file cluster.yaml --pod pod.yaml --replicas 10
+
+Flags:
+`
+
+// files is a repeatable string flag
+type files []string
+
+func (f *files) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *files) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		inputFiles files
+		podRef     string
+		replicas   int
+	)
+	flag.Var(&inputFiles, "file", "Path to a YAML or JSON file with the nodes, pods, namespaces and placement policies of the cluster. Can be repeated.")
+	flag.StringVar(&podRef, "pod", "", "The pod template of the replicas: the path to a YAML or JSON file with a pod, or the namespace/name of a pod of the input files.")
+	flag.IntVar(&replicas, "replicas", 1, "The number of replicas to place.")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if len(inputFiles) == 0 || podRef == "" {
+		flag.Usage()
+		return fmt.Errorf("--file and --pod are required")
+	}
+	if replicas < 0 {
+		return fmt.Errorf("--replicas must not be negative, got %d", replicas)
+	}
+
+	input, err := simulator.LoadFiles(inputFiles...)
+	if err != nil {
+		return err
+	}
+	template, err := getPodTemplate(input, podRef)
+	if err != nil {
+		return err
+	}
+
+	result, err := simulator.Simulate(context.Background(), input, template, replicas)
+	if err != nil {
+		return err
+	}
+	return result.Print(os.Stdout)
+}
+
+// getPodTemplate returns the pod of the input with the namespace/name, or the pod of the file
+func getPodTemplate(input *simulator.Input, podRef string) (*corev1.Pod, error) {
+	if _, err := os.Stat(podRef); err != nil {
+		namespace, name := metav1.NamespaceDefault, podRef
+		if i := strings.Index(podRef, "/"); i >= 0 {
+			namespace, name = podRef[:i], podRef[i+1:]
+		}
+		for _, pod := range input.Pods {
+			if pod.Namespace == namespace && pod.Name == name {
+				return pod, nil
+			}
+		}
+		return nil, fmt.Errorf("pod %s/%s not found in the input files", namespace, name)
+	}
+
+	podInput, err := simulator.LoadFiles(podRef)
+	if err != nil {
+		return nil, err
+	}
+	if len(podInput.Pods) != 1 {
+		return nil, fmt.Errorf("expected exactly one pod in %s, got %d", podRef, len(podInput.Pods))
+	}
+	pod := podInput.Pods[0]
+	if pod.Namespace == "" {
+		pod.Namespace = metav1.NamespaceDefault
+	}
+	return pod, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	ppclientset "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/clientset/versioned"
+	ppinformers "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/informers/externalversions"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/controller/rebalancer"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+// leaderElectionID is the name of the lease held by the active rebalancer
+const leaderElectionID = "placement-policy-rebalancer"
+
+func main() {
+	var (
+		kubeconfig   string
+		interval     time.Duration
+		maxEvictions int
+		dryRun       bool
+		defaultMode  string
+
+		enableLeaderElection    bool
+		leaderElectionNamespace string
+	)
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+	flag.DurationVar(&interval, "interval", time.Minute, "The interval between two rebalancing passes.")
+	flag.IntVar(&maxEvictions, "max-evictions", 1, "The maximum number of pods evicted per rebalancing pass.")
+	flag.BoolVar(&dryRun, "dry-run", false, "Only log the pods that would be evicted.")
+	flag.StringVar(&defaultMode, "default-enforcement-mode", string(v1alpha1.DefaultEnforcementMode), "The defaultEnforcementMode argument of the scheduler plugin.")
+	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
+		"Enable leader election for the rebalancer. Without it, only one instance may run, as concurrent instances evict pods independently.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "kube-system", "The namespace of the leader election lease.")
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	if interval <= 0 || maxEvictions <= 0 {
+		klog.ErrorS(nil, "interval and max-evictions must be positive", "interval", interval, "maxEvictions", maxEvictions)
+		os.Exit(1)
+	}
+	defaultEnforcementMode := v1alpha1.EnforcementMode(defaultMode)
+	if defaultEnforcementMode != v1alpha1.EnforcementModeBestEffort && defaultEnforcementMode != v1alpha1.EnforcementModeStrict {
+		klog.ErrorS(nil, "default-enforcement-mode must be BestEffort or Strict", "defaultEnforcementMode", defaultMode)
+		os.Exit(1)
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		klog.ErrorS(err, "unable to get kubeconfig")
+		os.Exit(1)
+	}
+	client := kubernetes.NewForConfigOrDie(config)
+	ppClient := ppclientset.NewForConfigOrDie(config)
+
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	namespaceInformer := informerFactory.Core().V1().Namespaces()
+	ppInformerFactory := ppinformers.NewSharedInformerFactory(ppClient, 0)
+	ppInformer := ppInformerFactory.Placementpolicy().V1alpha1().PlacementPolicies()
+	cppInformer := ppInformerFactory.Placementpolicy().V1alpha1().ClusterPlacementPolicies()
+
+	ppMgr := core.NewPlacementPolicyManager(
+		client,
+		ppClient,
+		nil,
+		ppInformer,
+		cppInformer,
+		podInformer.Lister(),
+		namespaceInformer.Lister(),
+		defaultEnforcementMode)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	eventBroadcaster := events.NewEventBroadcasterAdapter(client)
+	eventBroadcaster.StartRecordingToSink(ctx.Done())
+	defer eventBroadcaster.Shutdown()
+
+	controller := rebalancer.NewController(
+		client,
+		podInformer,
+		nodeInformer,
+		ppMgr,
+		eventBroadcaster.NewRecorder("placement-policy-rebalancer"),
+		interval,
+		maxEvictions,
+		dryRun,
+		namespaceInformer.Informer().HasSynced,
+		ppInformer.Informer().HasSynced,
+		cppInformer.Informer().HasSynced)
+
+	run := func(ctx context.Context) {
+		informerFactory.Start(ctx.Done())
+		ppInformerFactory.Start(ctx.Done())
+		controller.Run(ctx)
+	}
+	if !enableLeaderElection {
+		run(ctx)
+		return
+	}
+
+	id, err := os.Hostname()
+	if err != nil {
+		klog.ErrorS(err, "unable to get hostname")
+		os.Exit(1)
+	}
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: leaderElectionID, Namespace: leaderElectionNamespace},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: id},
+		},
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: run,
+			OnStoppedLeading: func() {
+				// the informers and the controller are not restarted, another instance takes over
+				if ctx.Err() == nil {
+					klog.ErrorS(nil, "leader election lost")
+					os.Exit(1)
+				}
+			},
+		},
+	})
+}
@@ -8,11 +8,14 @@ require (
 	k8s.io/apimachinery v0.22.2
 	k8s.io/client-go v0.22.2
 	k8s.io/code-generator v0.22.2
+	k8s.io/component-base v0.22.2
+	k8s.io/component-helpers v0.22.2
 	k8s.io/klog/hack/tools v0.0.0-20211022075437-9ad246211af1
 	k8s.io/klog/v2 v2.9.0
 	k8s.io/kube-openapi v0.0.0-20210421082810-95288971da7e
 	k8s.io/kube-scheduler v0.21.6
 	k8s.io/kubernetes v1.22.2
+	sigs.k8s.io/controller-runtime v0.10.3
 	sigs.k8s.io/e2e-framework v0.0.5
 	sigs.k8s.io/yaml v1.2.0
 )
@@ -147,6 +150,7 @@ require (
 	golang.org/x/time v0.0.0-20210723032227-1f47c861a9ac // indirect
 	golang.org/x/tools v0.1.12 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+	gomodules.xyz/jsonpatch/v2 v2.2.0 // indirect
 	google.golang.org/api v0.43.0 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/genproto v0.0.0-20210602131652-f16073e35f0c // indirect
@@ -163,8 +167,6 @@ require (
 	k8s.io/apiserver v0.22.2 // indirect
 	k8s.io/cloud-provider v0.22.2 // indirect
 	k8s.io/cluster-bootstrap v0.0.0 // indirect
-	k8s.io/component-base v0.22.2 // indirect
-	k8s.io/component-helpers v0.22.2 // indirect
 	k8s.io/csi-translation-lib v0.22.2 // indirect
 	k8s.io/gengo v0.0.0-20201214224949-b6c5ce23f027 // indirect
 	k8s.io/kube-aggregator v0.0.0 // indirect
@@ -175,7 +177,6 @@ require (
 	k8s.io/pod-security-admission v0.0.0 // indirect
 	k8s.io/utils v0.0.0-20210819203725-bdf08cb9a70a // indirect
 	sigs.k8s.io/apiserver-network-proxy/konnectivity-client v0.0.22 // indirect
-	sigs.k8s.io/controller-runtime v0.10.3 // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.1.2 // indirect
 )
 
@@ -51,6 +51,24 @@ app.kubernetes.io/instance: {{ .Release.Name }}
 component: scheduler
 {{- end }}
 
+{{/*
+Controller selector labels
+*/}}
+{{- define "placement-policy-scheduler-plugins.controllerSelectorLabels" -}}
+app.kubernetes.io/name: {{ include "placement-policy-scheduler-plugins.name" . }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+component: controller
+{{- end }}
+
+{{/*
+Rebalancer selector labels
+*/}}
+{{- define "placement-policy-scheduler-plugins.rebalancerSelectorLabels" -}}
+app.kubernetes.io/name: {{ include "placement-policy-scheduler-plugins.name" . }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+component: rebalancer
+{{- end }}
+
 {{/*
 Create the name of the service account to use
 */}}
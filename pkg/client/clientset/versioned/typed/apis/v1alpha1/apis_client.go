@@ -10,6 +10,7 @@ import (
 
 type PlacementpolicyV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ClusterPlacementPoliciesGetter
 	PlacementPoliciesGetter
 }
 
@@ -18,6 +19,10 @@ type PlacementpolicyV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *PlacementpolicyV1alpha1Client) ClusterPlacementPolicies() ClusterPlacementPolicyInterface {
+	return newClusterPlacementPolicies(c)
+}
+
 func (c *PlacementpolicyV1alpha1Client) PlacementPolicies(namespace string) PlacementPolicyInterface {
 	return newPlacementPolicies(c, namespace)
 }
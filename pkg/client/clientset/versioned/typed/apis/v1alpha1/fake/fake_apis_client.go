@@ -12,6 +12,10 @@ type FakePlacementpolicyV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakePlacementpolicyV1alpha1) ClusterPlacementPolicies() v1alpha1.ClusterPlacementPolicyInterface {
+	return &FakeClusterPlacementPolicies{c}
+}
+
 func (c *FakePlacementpolicyV1alpha1) PlacementPolicies(namespace string) v1alpha1.PlacementPolicyInterface {
 	return &FakePlacementPolicies{c, namespace}
 }
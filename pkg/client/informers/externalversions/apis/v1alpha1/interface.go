@@ -8,6 +8,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ClusterPlacementPolicies returns a ClusterPlacementPolicyInformer.
+	ClusterPlacementPolicies() ClusterPlacementPolicyInformer
 	// PlacementPolicies returns a PlacementPolicyInformer.
 	PlacementPolicies() PlacementPolicyInformer
 }
@@ -23,6 +25,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ClusterPlacementPolicies returns a ClusterPlacementPolicyInformer.
+func (v *version) ClusterPlacementPolicies() ClusterPlacementPolicyInformer {
+	return &clusterPlacementPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // PlacementPolicies returns a PlacementPolicyInformer.
 func (v *version) PlacementPolicies() PlacementPolicyInformer {
 	return &placementPolicyInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -37,6 +37,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=placement-policy.scheduling.x-k8s.io, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("clusterplacementpolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Placementpolicy().V1alpha1().ClusterPlacementPolicies().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("placementpolicies"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Placementpolicy().V1alpha1().PlacementPolicies().Informer()}, nil
 
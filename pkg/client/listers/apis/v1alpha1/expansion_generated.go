@@ -2,6 +2,10 @@
 
 package v1alpha1
 
+// ClusterPlacementPolicyListerExpansion allows custom methods to be added to
+// ClusterPlacementPolicyLister.
+type ClusterPlacementPolicyListerExpansion interface{}
+
 // PlacementPolicyListerExpansion allows custom methods to be added to
 // PlacementPolicyLister.
 type PlacementPolicyListerExpansion interface{}
@@ -0,0 +1,530 @@
+package rebalancer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/noderesources"
+)
+
+const (
+	// ReasonPlacementPolicyRebalanced is the event reason when a pod is evicted to restore
+	// the target size of its placement policy
+	ReasonPlacementPolicyRebalanced = "PlacementPolicyRebalanced"
+
+	eventActionEvict = "Evict"
+
+	// maxEvictionBackoff bounds the time the pods of a placement policy aren't evicted after
+	// the evicted pods were replaced on the node group they were evicted from
+	maxEvictionBackoff = time.Hour
+)
+
+// Controller periodically compares the placement of the pods with the target size of
+// the placement policy applied to them, and evicts the pods on the over-represented
+// node group so the scheduler places their replacements according to the policy.
+type Controller struct {
+	// client is a clientset for the kube API server
+	client kubernetes.Interface
+	// podLister is pod lister
+	podLister corelisters.PodLister
+	// nodeLister is node lister
+	nodeLister corelisters.NodeLister
+	// ppMgr is used to look up the placement policy of the pods and the pods it counts
+	ppMgr core.Manager
+	// eventRecorder records an event on the evicted pods
+	eventRecorder events.EventRecorder
+	// interval is the interval between two rebalancing passes
+	interval time.Duration
+	// maxEvictions is the maximum number of pods evicted per rebalancing pass
+	maxEvictions int
+	// dryRun only logs the pods that would be evicted
+	dryRun bool
+	// evictions are the pods evicted for each placement policy, whose replacements are
+	// checked before evicting more pods of the placement policy
+	evictions map[types.NamespacedName]*policyEvictions
+	// now returns the current time
+	now func() time.Time
+
+	cacheSynced []cache.InformerSynced
+}
+
+// policyEvictions are the pods evicted to restore the target size of a placement policy
+type policyEvictions struct {
+	// knownPods are the pods counted by the placement policy when the pods were evicted,
+	// the pods created since then being the replacements of the evicted pods
+	knownPods map[types.UID]bool
+	// owners are the controlling owners of the evicted pods, and the index of the node
+	// group the pods were evicted from
+	owners map[types.UID]int
+	// failures is the number of consecutive evictions whose replacements were placed on
+	// the node group the pods were evicted from
+	failures int
+	// retryAfter is the time until which the pods of the placement policy aren't evicted
+	retryAfter time.Time
+}
+
+// NewController returns a new rebalancing controller. The informers must be started
+// by the caller.
+func NewController(
+	client kubernetes.Interface,
+	podInformer coreinformers.PodInformer,
+	nodeInformer coreinformers.NodeInformer,
+	ppMgr core.Manager,
+	eventRecorder events.EventRecorder,
+	interval time.Duration,
+	maxEvictions int,
+	dryRun bool,
+	cacheSynced ...cache.InformerSynced) *Controller {
+	return &Controller{
+		client:        client,
+		podLister:     podInformer.Lister(),
+		nodeLister:    nodeInformer.Lister(),
+		ppMgr:         ppMgr,
+		eventRecorder: eventRecorder,
+		interval:      interval,
+		maxEvictions:  maxEvictions,
+		dryRun:        dryRun,
+		evictions:     make(map[types.NamespacedName]*policyEvictions),
+		now:           time.Now,
+		cacheSynced: append([]cache.InformerSynced{
+			podInformer.Informer().HasSynced,
+			nodeInformer.Informer().HasSynced,
+		}, cacheSynced...),
+	}
+}
+
+// Run runs a rebalancing pass every interval and blocks until the context is cancelled.
+func (c *Controller) Run(ctx context.Context) {
+	defer utilruntime.HandleCrash()
+
+	klog.InfoS("starting placement policy rebalancing controller", "interval", c.interval, "maxEvictions", c.maxEvictions, "dryRun", c.dryRun)
+	defer klog.InfoS("shutting down placement policy rebalancing controller")
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.cacheSynced...) {
+		klog.ErrorS(fmt.Errorf("WaitForCacheSync failed"), "Cannot sync caches")
+		return
+	}
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.rebalance(ctx); err != nil {
+			klog.ErrorS(err, "failed to rebalance placement policies")
+		}
+	}, c.interval)
+}
+
+// rebalance evicts up to maxEvictions pods of the placement policies whose placement
+// drifted from the target size.
+func (c *Controller) rebalance(ctx context.Context) error {
+	podList, err := c.podLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	nodeList, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	// the resources requested on the nodes, to check the replacements of the evicted pods fit
+	nodeInfos := newNodeInfos(nodeList, podList)
+
+	// group the pods by the placement policy applied to them. Only these pods are
+	// evicted, as their replacements are placed according to the same policy.
+	policies := make(map[types.NamespacedName]*v1alpha1.PlacementPolicy)
+	appliedPods := make(map[types.NamespacedName]map[types.UID]bool)
+	for _, pod := range podList {
+		if !isEvictable(pod) {
+			continue
+		}
+		pp, err := c.ppMgr.GetPlacementPolicyForPod(ctx, pod)
+		if err != nil {
+			return err
+		}
+		if pp == nil {
+			continue
+		}
+		key := types.NamespacedName{Namespace: pp.Namespace, Name: pp.Name}
+		if _, ok := policies[key]; !ok {
+			policies[key] = pp
+			appliedPods[key] = make(map[types.UID]bool)
+		}
+		appliedPods[key][pod.UID] = true
+	}
+
+	keys := make([]types.NamespacedName, 0, len(policies))
+	for key := range policies {
+		keys = append(keys, key)
+	}
+	for key := range c.evictions {
+		if _, ok := policies[key]; !ok {
+			delete(c.evictions, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	evictions := 0
+	for _, key := range keys {
+		if evictions >= c.maxEvictions {
+			klog.V(2).InfoS("reached the maximum number of evictions of the rebalancing pass", "maxEvictions", c.maxEvictions)
+			return nil
+		}
+		pp := policies[key]
+		policyPods, err := c.ppMgr.GetPodsForPlacementPolicy(ctx, pp)
+		if err != nil {
+			return err
+		}
+		groups, err := getNodeGroups(pp, nodeList)
+		if err != nil {
+			klog.ErrorS(err, "failed to get the node groups of the placement policy", "placementPolicy", klog.KObj(pp))
+			continue
+		}
+		if !c.checkReplacements(key, policyPods, groups) {
+			continue
+		}
+		candidates := getPodsToEvict(pp, policyPods, appliedPods[key], groups, nodeInfos)
+		var evicted []*corev1.Pod
+		for _, pod := range candidates {
+			if evictions >= c.maxEvictions {
+				break
+			}
+			if err := c.evictPod(ctx, pod, pp); err != nil {
+				klog.ErrorS(err, "failed to evict pod", "pod", klog.KObj(pod), "placementPolicy", klog.KObj(pp))
+				continue
+			}
+			evicted = append(evicted, pod)
+			evictions++
+		}
+		if len(evicted) > 0 && !c.dryRun {
+			c.recordEvictions(key, policyPods, evicted, groups)
+		}
+	}
+	return nil
+}
+
+// recordEvictions records the pods evicted for the placement policy, so their replacements
+// are checked before evicting more pods of the placement policy
+func (c *Controller) recordEvictions(key types.NamespacedName, policyPods, evicted []*corev1.Pod, groups *nodeGroups) {
+	e, ok := c.evictions[key]
+	if !ok {
+		e = &policyEvictions{}
+		c.evictions[key] = e
+	}
+	e.knownPods = make(map[types.UID]bool, len(policyPods))
+	for _, pod := range policyPods {
+		e.knownPods[pod.UID] = true
+	}
+	e.owners = make(map[types.UID]int, len(evicted))
+	for _, pod := range evicted {
+		if owner := metav1.GetControllerOf(pod); owner != nil {
+			e.owners[owner.UID] = groups.get(pod.Spec.NodeName)
+		}
+	}
+}
+
+// checkReplacements returns true if the pods of the placement policy can be evicted. Once
+// the pods evicted in a previous pass are replaced, the placement policy is backed off if
+// a replacement was placed on the node group its pod was evicted from, as evicting more
+// pods wouldn't change the placement.
+func (c *Controller) checkReplacements(key types.NamespacedName, policyPods []*corev1.Pod, groups *nodeGroups) bool {
+	e, ok := c.evictions[key]
+	if !ok {
+		return true
+	}
+	if e.owners != nil {
+		replacedOnSameNodes := false
+		for _, pod := range policyPods {
+			if e.knownPods[pod.UID] {
+				continue
+			}
+			owner := metav1.GetControllerOf(pod)
+			if owner == nil {
+				continue
+			}
+			evictedFrom, ok := e.owners[owner.UID]
+			if !ok {
+				continue
+			}
+			// wait for the replacement to be placed
+			if pod.Spec.NodeName == "" {
+				return false
+			}
+			if groups.get(pod.Spec.NodeName) == evictedFrom {
+				replacedOnSameNodes = true
+			}
+		}
+		if !replacedOnSameNodes {
+			delete(c.evictions, key)
+			return true
+		}
+
+		e.knownPods, e.owners = nil, nil
+		e.failures++
+		backoff := c.interval << e.failures
+		if backoff <= 0 || backoff > maxEvictionBackoff {
+			backoff = maxEvictionBackoff
+		}
+		e.retryAfter = c.now().Add(backoff)
+		klog.InfoS("evicted pods were replaced on the node group they were evicted from, backing off the rebalancing of the placement policy",
+			"placementPolicy", key, "failures", e.failures, "backoff", backoff)
+	}
+	return !c.now().Before(e.retryAfter)
+}
+
+// evictPod evicts the pod through the Eviction API, so the pod disruption budgets
+// of the pod are honored.
+func (c *Controller) evictPod(ctx context.Context, pod *corev1.Pod, pp *v1alpha1.PlacementPolicy) error {
+	if c.dryRun {
+		klog.InfoS("would evict pod to restore the target size of the placement policy", "pod", klog.KObj(pod), "node", pod.Spec.NodeName, "placementPolicy", klog.KObj(pp))
+		return nil
+	}
+	err := c.client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+	})
+	if apierrors.IsTooManyRequests(err) {
+		return fmt.Errorf("eviction disallowed by a pod disruption budget: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	klog.InfoS("evicted pod to restore the target size of the placement policy", "pod", klog.KObj(pod), "node", pod.Spec.NodeName, "placementPolicy", klog.KObj(pp))
+	if c.eventRecorder != nil {
+		c.eventRecorder.Eventf(pod, nil, corev1.EventTypeNormal, ReasonPlacementPolicyRebalanced, eventActionEvict,
+			"Evicted from node %s to restore the target size of placement policy %s", pod.Spec.NodeName, pp.Name)
+	}
+	return nil
+}
+
+// nodeGroups are the node groups the pods of a placement policy are placed on: the node groups
+// of the policy, or the nodes matching its node selector, followed by the nodes outside of them
+type nodeGroups struct {
+	// nodes are the indexes of the node groups of the nodes in a node group
+	nodes map[string]int
+	// outside is the index of the nodes outside of the node groups
+	outside int
+}
+
+// get returns the index of the node group of the node
+func (g *nodeGroups) get(nodeName string) int {
+	if group, ok := g.nodes[nodeName]; ok {
+		return group
+	}
+	return g.outside
+}
+
+// getNodeGroups returns the node groups of the placement policy
+func getNodeGroups(pp *v1alpha1.PlacementPolicy, nodeList []*corev1.Node) (*nodeGroups, error) {
+	groups := &nodeGroups{nodes: make(map[string]int)}
+	if len(pp.Spec.Policy.Groups) > 0 {
+		groupSelectors, err := core.GetNodeGroupSelectors(pp)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodeList {
+			if group := core.GetNodeGroup(node, groupSelectors); group >= 0 {
+				groups.nodes[node.Name] = group
+			}
+		}
+		groups.outside = len(groupSelectors)
+		return groups, nil
+	}
+
+	nodeSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.NodeSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse node selector: %w", err)
+	}
+	for _, node := range nodeList {
+		if nodeSelector.Matches(labels.Set(node.Labels)) {
+			groups.nodes[node.Name] = 0
+		}
+	}
+	groups.outside = 1
+	return groups, nil
+}
+
+// getNodeGroupTargets returns the number of pods the placement policy places on each of its
+// node groups out of totalPods, followed by the nodes outside of them, in hundredths of a pod
+func getNodeGroupTargets(pp *v1alpha1.PlacementPolicy, totalPods int) ([]int, error) {
+	if len(pp.Spec.Policy.Groups) > 0 {
+		return core.GetNodeGroupTargets(pp.Spec.Policy.Groups, totalPods)
+	}
+	targetSize, err := core.GetTargetSize(pp, totalPods)
+	if err != nil {
+		return nil, err
+	}
+	return []int{100 * targetSize, 100 * (totalPods - targetSize)}, nil
+}
+
+// newNodeInfos returns the node infos of the nodes with the pods running on them
+func newNodeInfos(nodeList []*corev1.Node, podList []*corev1.Pod) []*framework.NodeInfo {
+	nodeInfos := make(map[string]*framework.NodeInfo, len(nodeList))
+	result := make([]*framework.NodeInfo, 0, len(nodeList))
+	for _, node := range nodeList {
+		nodeInfo := framework.NewNodeInfo()
+		nodeInfo.SetNode(node)
+		nodeInfos[node.Name] = nodeInfo
+		result = append(result, nodeInfo)
+	}
+	for _, pod := range podList {
+		// completed pods no longer occupy a node
+		if !core.IsCountedPod(pod) {
+			continue
+		}
+		if nodeInfo, ok := nodeInfos[pod.Spec.NodeName]; ok {
+			nodeInfo.AddPod(pod)
+		}
+	}
+	return result
+}
+
+// getPodsToEvict returns the pods to evict to move the placement of the pods counted by the
+// placement policy toward its target size, in the order they should be evicted. The pods are
+// evicted from the node group furthest above its target, as long as it's a whole pod or more
+// above it, and their replacements are expected on the node group furthest below its target,
+// where the scheduler places them. Only the pods in appliedPods are evicted, and only if their
+// replacement fits on a node of that node group. The replacements are added to the node infos,
+// so the pods evicted for several placement policies don't count on the same free resources.
+func getPodsToEvict(pp *v1alpha1.PlacementPolicy, podList []*corev1.Pod, appliedPods map[types.UID]bool, groups *nodeGroups, nodeInfos []*framework.NodeInfo) []*corev1.Pod {
+	// with the PerOwner scope, the target size applies to each workload
+	scopes := map[types.UID][]*corev1.Pod{"": podList}
+	if pp.Spec.Policy.Scope == v1alpha1.ScopePerOwner {
+		scopes = core.GroupPodsByOwner(podList)
+	}
+
+	var podsToEvict []*corev1.Pod
+	for _, scopePods := range scopes {
+		targets, err := getNodeGroupTargets(pp, len(scopePods))
+		if err != nil {
+			klog.ErrorS(err, "failed to get the target size", "placementPolicy", klog.KObj(pp))
+			return nil
+		}
+		podsInGroup := make([][]*corev1.Pod, len(targets))
+		pending := false
+		for _, pod := range scopePods {
+			if pod.Spec.NodeName == "" || pod.DeletionTimestamp != nil {
+				pending = true
+				continue
+			}
+			group := groups.get(pod.Spec.NodeName)
+			podsInGroup[group] = append(podsInGroup[group], pod)
+		}
+		// the scheduler is still placing pods of the scope, or the pods evicted in a
+		// previous pass are being replaced
+		if pending {
+			continue
+		}
+
+		// surplus is the number of pods above the target of each node group, in hundredths of a pod
+		surplus := make([]int, len(targets))
+		candidates := make([][]*corev1.Pod, len(targets))
+		for i, target := range targets {
+			surplus[i] = 100*len(podsInGroup[i]) - target
+			candidates[i] = filterAppliedPods(podsInGroup[i], appliedPods)
+			// evict the most recently created pods first
+			sort.Slice(candidates[i], func(a, b int) bool { return newerFirst(candidates[i][a], candidates[i][b]) })
+		}
+		for {
+			from, to := 0, 0
+			for i := range surplus {
+				if surplus[i] > surplus[from] {
+					from = i
+				}
+				if surplus[i] < surplus[to] {
+					to = i
+				}
+			}
+			if surplus[from] < 100 {
+				break
+			}
+			evicted := false
+			for len(candidates[from]) > 0 && !evicted {
+				pod := candidates[from][0]
+				candidates[from] = candidates[from][1:]
+				// the replacement of the pod would be placed on the same node group again
+				if reserveNode(pod, to, groups, nodeInfos) {
+					podsToEvict = append(podsToEvict, pod)
+					evicted = true
+				}
+			}
+			if !evicted {
+				break
+			}
+			surplus[from] -= 100
+			surplus[to] += 100
+		}
+	}
+
+	sort.Slice(podsToEvict, func(i, j int) bool { return newerFirst(podsToEvict[i], podsToEvict[j]) })
+	return podsToEvict
+}
+
+// reserveNode adds the replacement of the pod to a schedulable node of the node group the pod
+// should move to with enough free resources, and returns false if there is no such node
+func reserveNode(pod *corev1.Pod, to int, groups *nodeGroups, nodeInfos []*framework.NodeInfo) bool {
+	for _, nodeInfo := range nodeInfos {
+		node := nodeInfo.Node()
+		if node.Spec.Unschedulable || groups.get(node.Name) != to {
+			continue
+		}
+		if len(noderesources.Fits(pod, nodeInfo, true)) > 0 {
+			continue
+		}
+		replacement := pod.DeepCopy()
+		replacement.Spec.NodeName = node.Name
+		nodeInfo.AddPod(replacement)
+		return true
+	}
+	return false
+}
+
+// newerFirst orders the pods from the most recently created to the oldest
+func newerFirst(a, b *corev1.Pod) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return b.CreationTimestamp.Before(&a.CreationTimestamp)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+func filterAppliedPods(podList []*corev1.Pod, appliedPods map[types.UID]bool) []*corev1.Pod {
+	var filtered []*corev1.Pod
+	for _, pod := range podList {
+		if appliedPods[pod.UID] {
+			filtered = append(filtered, pod)
+		}
+	}
+	return filtered
+}
+
+// isEvictable returns true if the pod is running on a node and is recreated by its
+// controlling owner once evicted. DaemonSet pods are bound to their node and are
+// never evicted.
+func isEvictable(pod *corev1.Pod) bool {
+	if pod.Spec.NodeName == "" || pod.DeletionTimestamp != nil {
+		return false
+	}
+	if pod.Status.Phase != corev1.PodRunning && pod.Status.Phase != corev1.PodPending {
+		return false
+	}
+	owner := metav1.GetControllerOf(pod)
+	return owner != nil && owner.Kind != "DaemonSet"
+}
@@ -0,0 +1,431 @@
+package rebalancer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	ppfake "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/clientset/versioned/fake"
+	ppinformers "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/informers/externalversions"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	testNodes = []*corev1.Node{
+		newTestNode("node1", "want", 110),
+		newTestNode("node2", "unwant", 110),
+	}
+	testCreationTime = time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newTestNode(name, label string, pods int64) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"node": label}},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourcePods: *resource.NewQuantity(pods, resource.DecimalSI),
+		}},
+	}
+}
+
+func newTestPlacementPolicy(targetSize intstr.IntOrString, scope v1alpha1.Scope) *v1alpha1.PlacementPolicy {
+	return &v1alpha1.PlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "default"},
+		Spec: v1alpha1.PlacementPolicySpec{
+			Weight:          v1alpha1.DefaultWeight,
+			EnforcementMode: v1alpha1.EnforcementModeBestEffort,
+			PodSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+			NodeSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+			Policy:          &v1alpha1.Policy{Action: v1alpha1.ActionMust, TargetSize: &targetSize, Scope: scope},
+		},
+	}
+}
+
+// newTestNodeGroupPlacementPolicy returns a placement policy with a node group per node label
+// and target size
+func newTestNodeGroupPlacementPolicy(groups ...string) *v1alpha1.PlacementPolicy {
+	pp := newTestPlacementPolicy(intstr.IntOrString{}, v1alpha1.ScopeAll)
+	pp.Spec.NodeSelector = nil
+	pp.Spec.Policy.TargetSize = nil
+	for i := 0; i+1 < len(groups); i += 2 {
+		targetSize := intstr.FromString(groups[i+1])
+		pp.Spec.Policy.Groups = append(pp.Spec.Policy.Groups, v1alpha1.NodeGroup{
+			Name:         groups[i],
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"node": groups[i]}},
+			TargetSize:   &targetSize,
+		})
+	}
+	return pp
+}
+
+// newTestPod returns a running pod of the given replica set, created age minutes
+// after testCreationTime
+func newTestPod(name, nodeName, owner string, age int) *corev1.Pod {
+	controller := true
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			UID:               types.UID(name),
+			Labels:            map[string]string{"app": "nginx"},
+			CreationTimestamp: metav1.NewTime(testCreationTime.Add(time.Duration(age) * time.Minute)),
+			OwnerReferences:   []metav1.OwnerReference{{Kind: "ReplicaSet", Name: owner, UID: types.UID(owner), Controller: &controller}},
+		},
+		Spec:   corev1.PodSpec{NodeName: nodeName},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func TestGetPodsToEvict(t *testing.T) {
+	tests := []struct {
+		name        string
+		pp          *v1alpha1.PlacementPolicy
+		nodeList    []*corev1.Node
+		podList     []*corev1.Pod
+		appliedPods []string
+		want        []string
+	}{
+		{
+			name: "placement matches the target size",
+			pp:   newTestPlacementPolicy(intstr.FromString("50%"), v1alpha1.ScopeAll),
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node1", "rs1", 1),
+				newTestPod("pod2", "node2", "rs1", 2),
+			},
+			appliedPods: []string{"pod1", "pod2"},
+		},
+		{
+			name: "surplus of pods on matching nodes",
+			pp:   newTestPlacementPolicy(intstr.FromString("25%"), v1alpha1.ScopeAll),
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node1", "rs1", 1),
+				newTestPod("pod2", "node1", "rs1", 2),
+				newTestPod("pod3", "node1", "rs1", 3),
+				newTestPod("pod4", "node2", "rs1", 4),
+			},
+			appliedPods: []string{"pod1", "pod2", "pod3", "pod4"},
+			want:        []string{"pod3", "pod2"},
+		},
+		{
+			name: "surplus of pods on other nodes",
+			pp:   newTestPlacementPolicy(intstr.FromString("75%"), v1alpha1.ScopeAll),
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node1", "rs1", 1),
+				newTestPod("pod2", "node2", "rs1", 2),
+				newTestPod("pod3", "node2", "rs1", 3),
+				newTestPod("pod4", "node2", "rs1", 4),
+			},
+			appliedPods: []string{"pod1", "pod2", "pod3", "pod4"},
+			want:        []string{"pod4", "pod3"},
+		},
+		{
+			name: "pods applied another placement policy are not evicted",
+			pp:   newTestPlacementPolicy(intstr.FromString("75%"), v1alpha1.ScopeAll),
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node1", "rs1", 1),
+				newTestPod("pod2", "node2", "rs1", 2),
+				newTestPod("pod3", "node2", "rs1", 3),
+				newTestPod("pod4", "node2", "rs1", 4),
+			},
+			appliedPods: []string{"pod1", "pod2"},
+			want:        []string{"pod2"},
+		},
+		{
+			name: "pending pods defer the rebalancing",
+			pp:   newTestPlacementPolicy(intstr.FromString("75%"), v1alpha1.ScopeAll),
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node1", "rs1", 1),
+				newTestPod("pod2", "node2", "rs1", 2),
+				newTestPod("pod3", "", "rs1", 3),
+			},
+			appliedPods: []string{"pod1", "pod2"},
+		},
+		{
+			name:     "no matching nodes",
+			pp:       newTestPlacementPolicy(intstr.FromString("75%"), v1alpha1.ScopeAll),
+			nodeList: []*corev1.Node{newTestNode("node2", "unwant", 110)},
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node2", "rs1", 1),
+				newTestPod("pod2", "node2", "rs1", 2),
+			},
+			appliedPods: []string{"pod1", "pod2"},
+		},
+		{
+			name: "evictions limited by the room on the matching nodes",
+			pp:   newTestPlacementPolicy(intstr.FromString("75%"), v1alpha1.ScopeAll),
+			nodeList: []*corev1.Node{
+				newTestNode("node1", "want", 2),
+				newTestNode("node2", "unwant", 110),
+				newTestNode("node3", "want", 1),
+			},
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node1", "rs1", 1),
+				newTestPod("pod2", "node1", "rs1", 2),
+				newTestPod("pod3", "node2", "rs1", 3),
+				newTestPod("pod4", "node2", "rs1", 4),
+				newTestPod("pod5", "node2", "rs1", 5),
+				newTestPod("pod6", "node2", "rs1", 6),
+			},
+			appliedPods: []string{"pod1", "pod2", "pod3", "pod4", "pod5", "pod6"},
+			want:        []string{"pod6"},
+		},
+		{
+			name: "unschedulable matching nodes",
+			pp:   newTestPlacementPolicy(intstr.FromString("75%"), v1alpha1.ScopeAll),
+			nodeList: []*corev1.Node{
+				func() *corev1.Node {
+					node := newTestNode("node1", "want", 110)
+					node.Spec.Unschedulable = true
+					return node
+				}(),
+				newTestNode("node2", "unwant", 110),
+			},
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node1", "rs1", 1),
+				newTestPod("pod2", "node2", "rs1", 2),
+			},
+			appliedPods: []string{"pod1", "pod2"},
+		},
+		{
+			name: "node groups within one pod of their targets",
+			pp:   newTestNodeGroupPlacementPolicy("want", "50%", "unwant", "50%"),
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node1", "rs1", 1),
+				newTestPod("pod2", "node1", "rs1", 2),
+				newTestPod("pod3", "node2", "rs1", 3),
+			},
+			appliedPods: []string{"pod1", "pod2", "pod3"},
+		},
+		{
+			name: "surplus of pods on a node group",
+			pp:   newTestNodeGroupPlacementPolicy("want", "50%", "unwant", "50%"),
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node1", "rs1", 1),
+				newTestPod("pod2", "node1", "rs1", 2),
+				newTestPod("pod3", "node1", "rs1", 3),
+				newTestPod("pod4", "node1", "rs1", 4),
+			},
+			appliedPods: []string{"pod1", "pod2", "pod3", "pod4"},
+			want:        []string{"pod4", "pod3"},
+		},
+		{
+			name: "surplus of pods on a node group moved outside of the node groups",
+			pp:   newTestNodeGroupPlacementPolicy("want", "25%"),
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node1", "rs1", 1),
+				newTestPod("pod2", "node1", "rs1", 2),
+				newTestPod("pod3", "node1", "rs1", 3),
+				newTestPod("pod4", "node2", "rs1", 4),
+			},
+			appliedPods: []string{"pod1", "pod2", "pod3", "pod4"},
+			want:        []string{"pod3", "pod2"},
+		},
+		{
+			name: "per owner scope rebalances each workload",
+			pp:   newTestPlacementPolicy(intstr.FromString("50%"), v1alpha1.ScopePerOwner),
+			podList: []*corev1.Pod{
+				newTestPod("pod1", "node1", "rs1", 1),
+				newTestPod("pod2", "node1", "rs1", 2),
+				newTestPod("pod3", "node1", "rs2", 3),
+				newTestPod("pod4", "node2", "rs2", 4),
+			},
+			appliedPods: []string{"pod1", "pod2", "pod3", "pod4"},
+			want:        []string{"pod2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appliedPods := make(map[types.UID]bool)
+			for _, name := range tt.appliedPods {
+				appliedPods[types.UID(name)] = true
+			}
+			nodeList := tt.nodeList
+			if nodeList == nil {
+				nodeList = testNodes
+			}
+			groups, err := getNodeGroups(tt.pp, nodeList)
+			if err != nil {
+				t.Fatalf("getNodeGroups() error = %v", err)
+			}
+			podsToEvict := getPodsToEvict(tt.pp, tt.podList, appliedPods, groups, newNodeInfos(nodeList, tt.podList))
+			var got []string
+			for _, pod := range podsToEvict {
+				got = append(got, pod.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getPodsToEvict() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	pp := newTestPlacementPolicy(intstr.FromString("40%"), v1alpha1.ScopeAll)
+	pods := []*corev1.Pod{
+		newTestPod("pod1", "node1", "rs1", 1),
+		newTestPod("pod2", "node1", "rs1", 2),
+		newTestPod("pod3", "node1", "rs1", 3),
+		newTestPod("pod4", "node2", "rs1", 4),
+		// pods without a controlling owner are not recreated once evicted
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod5", Namespace: "default", UID: "pod5", Labels: map[string]string{"app": "nginx"}},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		maxEvictions int
+		dryRun       bool
+		want         []string
+	}{
+		{
+			name:         "evict the surplus pods",
+			maxEvictions: 10,
+			want:         []string{"pod3", "pod2"},
+		},
+		{
+			name:         "evictions limited per pass",
+			maxEvictions: 1,
+			want:         []string{"pod3"},
+		},
+		{
+			name:         "dry run",
+			maxEvictions: 10,
+			dryRun:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, client, _ := newTestController(t, pods, []*v1alpha1.PlacementPolicy{pp}, tt.maxEvictions, tt.dryRun)
+			if err := c.rebalance(context.Background()); err != nil {
+				t.Fatalf("rebalance() error = %v", err)
+			}
+
+			if got := getEvictedPods(client); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rebalance() evicted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebalanceBackoff(t *testing.T) {
+	pp := newTestPlacementPolicy(intstr.FromString("75%"), v1alpha1.ScopeAll)
+	pods := []*corev1.Pod{
+		newTestPod("pod1", "node1", "rs1", 1),
+		newTestPod("pod2", "node2", "rs1", 2),
+		newTestPod("pod3", "node2", "rs1", 3),
+		newTestPod("pod4", "node2", "rs1", 4),
+	}
+	c, client, podIndexer := newTestController(t, pods, []*v1alpha1.PlacementPolicy{pp}, 1, false)
+	now := testCreationTime.Add(time.Hour)
+	c.now = func() time.Time { return now }
+
+	// replace the evicted pod with a pod of the same replica set on the given node
+	replace := func(evicted, replacement, nodeName string, age int) {
+		t.Helper()
+		obj, ok, err := podIndexer.GetByKey("default/" + evicted)
+		if err != nil || !ok {
+			t.Fatalf("failed to get pod %s: %v", evicted, err)
+		}
+		if err := podIndexer.Delete(obj); err != nil {
+			t.Fatalf("failed to delete pod: %v", err)
+		}
+		pod := newTestPod(replacement, nodeName, "rs1", age)
+		if err := podIndexer.Add(pod); err != nil {
+			t.Fatalf("failed to add pod: %v", err)
+		}
+		if err := client.Tracker().Add(pod); err != nil {
+			t.Fatalf("failed to add pod: %v", err)
+		}
+	}
+	rebalance := func(step string, want ...string) {
+		t.Helper()
+		client.ClearActions()
+		if err := c.rebalance(context.Background()); err != nil {
+			t.Fatalf("%s: rebalance() error = %v", step, err)
+		}
+		if got := getEvictedPods(client); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: rebalance() evicted %v, want %v", step, got, want)
+		}
+	}
+
+	rebalance("surplus of pods on other nodes", "pod4")
+	replace("pod4", "pod5", "node2", 5)
+	rebalance("replacement placed on the node group it was evicted from")
+	now = now.Add(c.interval)
+	rebalance("backing off")
+	now = now.Add(c.interval)
+	rebalance("backoff expired", "pod5")
+	replace("pod5", "pod6", "node2", 6)
+	rebalance("replacement placed on the node group it was evicted from again")
+	now = now.Add(3 * c.interval)
+	rebalance("backoff doubled")
+	now = now.Add(c.interval)
+	rebalance("doubled backoff expired", "pod6")
+	replace("pod6", "pod7", "node1", 7)
+	rebalance("replacement placed on the matching nodes", "pod3")
+}
+
+func getEvictedPods(client *fake.Clientset) []string {
+	var evicted []string
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "create" && action.GetSubresource() == "eviction" {
+			eviction := action.(clienttesting.CreateAction).GetObject().(*policyv1.Eviction)
+			evicted = append(evicted, eviction.Name)
+		}
+	}
+	return evicted
+}
+
+func newTestController(t *testing.T, pods []*corev1.Pod, ppList []*v1alpha1.PlacementPolicy, maxEvictions int, dryRun bool) (*Controller, *fake.Clientset, cache.Indexer) {
+	objects := make([]runtime.Object, 0, len(pods))
+	for _, pod := range pods {
+		objects = append(objects, pod)
+	}
+	client := fake.NewSimpleClientset(objects...)
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	namespaceInformer := informerFactory.Core().V1().Namespaces()
+	ppClient := ppfake.NewSimpleClientset()
+	ppInformerFactory := ppinformers.NewSharedInformerFactory(ppClient, 0)
+	ppInformer := ppInformerFactory.Placementpolicy().V1alpha1().PlacementPolicies()
+	cppInformer := ppInformerFactory.Placementpolicy().V1alpha1().ClusterPlacementPolicies()
+
+	for _, pod := range pods {
+		if err := podInformer.Informer().GetIndexer().Add(pod); err != nil {
+			t.Fatalf("failed to add pod: %v", err)
+		}
+	}
+	for _, node := range testNodes {
+		if err := nodeInformer.Informer().GetIndexer().Add(node); err != nil {
+			t.Fatalf("failed to add node: %v", err)
+		}
+	}
+	for _, pp := range ppList {
+		if err := ppInformer.Informer().GetIndexer().Add(pp); err != nil {
+			t.Fatalf("failed to add placement policy: %v", err)
+		}
+	}
+
+	ppMgr := core.NewPlacementPolicyManager(client, ppClient, nil, ppInformer, cppInformer,
+		podInformer.Lister(), namespaceInformer.Lister(), v1alpha1.DefaultEnforcementMode)
+	return NewController(client, podInformer, nodeInformer, ppMgr, nil, time.Minute, maxEvictions, dryRun), client, podInformer.Informer().GetIndexer()
+}
@@ -0,0 +1,422 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	ppclientset "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/clientset/versioned"
+	ppinformers "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/informers/externalversions/apis/v1alpha1"
+	pplisters "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/listers/apis/v1alpha1"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/metrics"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+// +kubebuilder:rbac:groups=placement-policy.scheduling.x-k8s.io,resources=placementpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=placement-policy.scheduling.x-k8s.io,resources=placementpolicies/status,verbs=get;update;patch
+
+// Controller keeps the status of placement policies up to date with the
+// current placement of the pods they select.
+type Controller struct {
+	// ppClient is a placementPolicy client
+	ppClient ppclientset.Interface
+	// ppLister is placementPolicy lister
+	ppLister pplisters.PlacementPolicyLister
+	// nodeLister is node lister
+	nodeLister corelisters.NodeLister
+	// ppMgr is used to look up the pods counted by the scheduler plugin
+	ppMgr core.Manager
+	// queue holds the namespace/name keys of the placement policies to sync
+	queue workqueue.RateLimitingInterface
+
+	cacheSynced []cache.InformerSynced
+}
+
+// NewController returns a new status controller. The event handlers are registered
+// on the provided informers, so it must be called before the informers are started.
+func NewController(
+	ppClient ppclientset.Interface,
+	ppInformer ppinformers.PlacementPolicyInformer,
+	podInformer coreinformers.PodInformer,
+	nodeInformer coreinformers.NodeInformer,
+	namespaceInformer coreinformers.NamespaceInformer,
+	ppMgr core.Manager) *Controller {
+	c := &Controller{
+		ppClient:   ppClient,
+		ppLister:   ppInformer.Lister(),
+		nodeLister: nodeInformer.Lister(),
+		ppMgr:      ppMgr,
+		queue:      workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "placementpolicy-status"),
+		cacheSynced: []cache.InformerSynced{
+			ppInformer.Informer().HasSynced,
+			podInformer.Informer().HasSynced,
+			nodeInformer.Informer().HasSynced,
+			namespaceInformer.Informer().HasSynced,
+		},
+	}
+
+	ppInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueuePlacementPolicy,
+		UpdateFunc: func(_, newObj interface{}) { c.enqueuePlacementPolicy(newObj) },
+		// the gauges of a deleted placement policy are removed when its key is synced
+		DeleteFunc: c.enqueuePlacementPolicy,
+	})
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueuePlacementPoliciesForPod,
+		UpdateFunc: func(_, newObj interface{}) { c.enqueuePlacementPoliciesForPod(newObj) },
+		DeleteFunc: c.enqueuePlacementPoliciesForPod,
+	})
+	// a change in node labels can move pods in or out of the matching node group
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(interface{}) { c.enqueueAllPlacementPolicies() },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, newNode := oldObj.(*corev1.Node), newObj.(*corev1.Node)
+			if !reflect.DeepEqual(oldNode.Labels, newNode.Labels) {
+				c.enqueueAllPlacementPolicies()
+			}
+		},
+		DeleteFunc: func(interface{}) { c.enqueueAllPlacementPolicies() },
+	})
+	// a change in namespace labels can move pods in or out of the policies with a namespace selector
+	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(interface{}) { c.enqueuePlacementPoliciesWithNamespaceSelector() },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNamespace, newNamespace := oldObj.(*corev1.Namespace), newObj.(*corev1.Namespace)
+			if !reflect.DeepEqual(oldNamespace.Labels, newNamespace.Labels) {
+				c.enqueuePlacementPoliciesWithNamespaceSelector()
+			}
+		},
+		DeleteFunc: func(interface{}) { c.enqueuePlacementPoliciesWithNamespaceSelector() },
+	})
+
+	return c
+}
+
+// Run starts the workers and blocks until the context is cancelled.
+func (c *Controller) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.InfoS("starting placement policy status controller")
+	defer klog.InfoS("shutting down placement policy status controller")
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.cacheSynced...) {
+		klog.ErrorS(fmt.Errorf("WaitForCacheSync failed"), "Cannot sync caches")
+		return
+	}
+
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.worker, time.Second)
+	}
+	<-ctx.Done()
+}
+
+func (c *Controller) worker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.sync(ctx, key.(string)); err != nil {
+		klog.ErrorS(err, "failed to sync placement policy status", "placementPolicy", key)
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) sync(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	pp, err := c.ppLister.PlacementPolicies(namespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			metrics.DeletePolicyStatus(namespace, name)
+			return nil
+		}
+		return err
+	}
+	// policies missing required fields are never applied by the scheduler plugin
+	if pp.Spec.PodSelector == nil || pp.Spec.Policy == nil || (pp.Spec.NodeSelector == nil && len(pp.Spec.Policy.Groups) == 0) {
+		metrics.DeletePolicyStatus(namespace, name)
+		return nil
+	}
+	// compute the status against the effective spec used by the scheduler plugin
+	effectivePP, err := c.ppMgr.GetPlacementPolicy(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	podList, err := c.ppMgr.GetPodsForPlacementPolicy(ctx, effectivePP)
+	if err != nil {
+		return err
+	}
+	nodeList, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	status, err := computeStatus(effectivePP, podList, nodeList)
+	if err != nil {
+		return err
+	}
+	metrics.SetPolicyStatus(namespace, name, status)
+	if equality.Semantic.DeepEqual(pp.Status, status) {
+		return nil
+	}
+
+	newPP := pp.DeepCopy()
+	newPP.Status = status
+	_, err = c.ppClient.PlacementpolicyV1alpha1().PlacementPolicies(namespace).UpdateStatus(ctx, newPP, metav1.UpdateOptions{})
+	return err
+}
+
+// computeStatus returns the status of the placement policy given the pods counted by
+// the policy, as returned by core.GetPodsForPlacementPolicy, and all nodes in the cluster
+func computeStatus(pp *v1alpha1.PlacementPolicy, podList []*corev1.Pod, nodeList []*corev1.Node) (v1alpha1.PlacementPolicyStatus, error) {
+	status := *pp.Status.DeepCopy()
+	if len(pp.Spec.Policy.Groups) > 0 {
+		return computeNodeGroupStatus(pp, status, podList, nodeList)
+	}
+
+	nodeSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.NodeSelector)
+	if err != nil {
+		return status, err
+	}
+	nodeWithMatchingLabels := make(map[string]bool)
+	for _, node := range nodeList {
+		if nodeSelector.Matches(labels.Set(node.Labels)) {
+			nodeWithMatchingLabels[node.Name] = true
+		}
+	}
+
+	podsOnMatchingNodes := 0
+	for _, pod := range podList {
+		if nodeWithMatchingLabels[pod.Spec.NodeName] {
+			podsOnMatchingNodes++
+		}
+	}
+	matchedPods := len(podList)
+
+	// with the PerOwner scope, the target is the sum of the targets of each workload, and
+	// the placement drifted if any workload drifted from its own target
+	groups := getPodsInScopes(pp, podList)
+	targetSize, driftedGroups := 0, 0
+	for _, group := range groups {
+		groupTargetSize, err := core.GetTargetSize(pp, len(group))
+		if err != nil {
+			return status, err
+		}
+		targetSize += groupTargetSize
+		groupPodsOnMatchingNodes := 0
+		for _, pod := range group {
+			if nodeWithMatchingLabels[pod.Spec.NodeName] {
+				groupPodsOnMatchingNodes++
+			}
+		}
+		if groupPodsOnMatchingNodes != groupTargetSize {
+			driftedGroups++
+		}
+	}
+
+	status.ObservedGeneration = pp.Generation
+	status.MatchedPods = int32(matchedPods)
+	status.PodsOnMatchingNodes = int32(podsOnMatchingNodes)
+	status.TargetPods = int32(targetSize)
+	status.Groups = nil
+	setSatisfiedCondition(pp, &status, driftedGroups, len(groups),
+		fmt.Sprintf("%d of %d pods are on matching nodes", podsOnMatchingNodes, matchedPods))
+
+	return status, nil
+}
+
+// computeNodeGroupStatus returns the status of a placement policy with node groups. As the pods
+// are distributed in proportion to the target sizes of the node groups, the placement drifted if
+// a node group, or the nodes outside of the node groups, is a whole pod or more away from its target.
+func computeNodeGroupStatus(pp *v1alpha1.PlacementPolicy, status v1alpha1.PlacementPolicyStatus, podList []*corev1.Pod, nodeList []*corev1.Node) (v1alpha1.PlacementPolicyStatus, error) {
+	groupSelectors, err := core.GetNodeGroupSelectors(pp)
+	if err != nil {
+		return status, err
+	}
+	nodeGroups := make(map[string]int, len(nodeList))
+	for _, node := range nodeList {
+		if group := core.GetNodeGroup(node, groupSelectors); group >= 0 {
+			nodeGroups[node.Name] = group
+		}
+	}
+
+	// the pods and the targets of each node group, followed by the nodes outside of the
+	// node groups. The targets are in hundredths of a pod.
+	groups := len(groupSelectors)
+	podsInGroup := make([]int, groups+1)
+	targets := make([]int, groups+1)
+	scopes := getPodsInScopes(pp, podList)
+	driftedScopes := 0
+	for _, scopePods := range scopes {
+		scopeTargets, err := core.GetNodeGroupTargets(pp.Spec.Policy.Groups, len(scopePods))
+		if err != nil {
+			return status, err
+		}
+		scopePodsInGroup := make([]int, groups+1)
+		for _, pod := range scopePods {
+			if pod.Spec.NodeName == "" {
+				continue
+			}
+			group, ok := nodeGroups[pod.Spec.NodeName]
+			if !ok {
+				group = groups
+			}
+			scopePodsInGroup[group]++
+		}
+		drifted := false
+		for i := range targets {
+			podsInGroup[i] += scopePodsInGroup[i]
+			targets[i] += scopeTargets[i]
+			if diff := 100*scopePodsInGroup[i] - scopeTargets[i]; diff <= -100 || diff >= 100 {
+				drifted = true
+			}
+		}
+		if drifted {
+			driftedScopes++
+		}
+	}
+
+	status.ObservedGeneration = pp.Generation
+	status.MatchedPods = int32(len(podList))
+	status.PodsOnMatchingNodes = 0
+	status.TargetPods = 0
+	status.Groups = make([]v1alpha1.NodeGroupStatus, 0, groups)
+	for i, group := range pp.Spec.Policy.Groups {
+		groupStatus := v1alpha1.NodeGroupStatus{
+			Name:                group.Name,
+			PodsOnMatchingNodes: int32(podsInGroup[i]),
+			TargetPods:          int32(targets[i] / 100),
+		}
+		status.PodsOnMatchingNodes += groupStatus.PodsOnMatchingNodes
+		status.TargetPods += groupStatus.TargetPods
+		status.Groups = append(status.Groups, groupStatus)
+	}
+	setSatisfiedCondition(pp, &status, driftedScopes, len(scopes),
+		fmt.Sprintf("%d of %d pods are on the nodes of the node groups", status.PodsOnMatchingNodes, status.MatchedPods))
+
+	return status, nil
+}
+
+// setSatisfiedCondition sets the Satisfied condition of the status, the placement having drifted
+// if any of the scopes of the placement policy drifted from its target
+func setSatisfiedCondition(pp *v1alpha1.PlacementPolicy, status *v1alpha1.PlacementPolicyStatus, driftedScopes, scopes int, message string) {
+	condition := metav1.Condition{
+		Type:               v1alpha1.PlacementPolicyConditionSatisfied,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: pp.Generation,
+		Reason:             v1alpha1.PlacementPolicyReasonSatisfied,
+		Message:            message,
+	}
+	if driftedScopes > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.PlacementPolicyReasonDrifted
+		condition.Message = fmt.Sprintf("%s, want %d", message, status.TargetPods)
+		if pp.Spec.Policy.Scope == v1alpha1.ScopePerOwner {
+			condition.Message += fmt.Sprintf(" (%d of %d workloads drifted from their target)", driftedScopes, scopes)
+		}
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
+
+// getPodsInScopes returns the pods the target size is computed over: the pods of each
+// workload with the PerOwner scope, otherwise all the pods
+func getPodsInScopes(pp *v1alpha1.PlacementPolicy, pods []*corev1.Pod) map[types.UID][]*corev1.Pod {
+	if pp.Spec.Policy.Scope == v1alpha1.ScopePerOwner {
+		return core.GroupPodsByOwner(pods)
+	}
+	return map[types.UID][]*corev1.Pod{"": pods}
+}
+
+func (c *Controller) enqueuePlacementPolicy(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *Controller) enqueuePlacementPoliciesForPod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		if pod, ok = tombstone.Obj.(*corev1.Pod); !ok {
+			return
+		}
+	}
+
+	ppList, err := c.ppLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, pp := range ppList {
+		// pods in other namespaces are only counted by policies with a namespace selector
+		if pp.Namespace != pod.Namespace && pp.Spec.NamespaceSelector == nil {
+			continue
+		}
+		if matches, err := utils.HasMatchingLabels(pod.Labels, pp.Spec.PodSelector); err != nil || !matches {
+			continue
+		}
+		c.enqueuePlacementPolicy(pp)
+	}
+}
+
+func (c *Controller) enqueueAllPlacementPolicies() {
+	ppList, err := c.ppLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, pp := range ppList {
+		c.enqueuePlacementPolicy(pp)
+	}
+}
+
+func (c *Controller) enqueuePlacementPoliciesWithNamespaceSelector() {
+	ppList, err := c.ppLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, pp := range ppList {
+		if pp.Spec.NamespaceSelector != nil {
+			c.enqueuePlacementPolicy(pp)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package status
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	ppfake "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/clientset/versioned/fake"
+	ppinformers "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/informers/externalversions"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/metrics"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestComputeStatus(t *testing.T) {
+	nodeList := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"node": "want"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"node": "want"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"node": "unwant"}}},
+	}
+	makePP := func(action v1alpha1.Action, targetSize intstr.IntOrString) *v1alpha1.PlacementPolicy {
+		return &v1alpha1.PlacementPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "pp", Generation: 2},
+			Spec: v1alpha1.PlacementPolicySpec{
+				PodSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+				NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+				Policy:       &v1alpha1.Policy{Action: action, TargetSize: &targetSize},
+			},
+		}
+	}
+	makePod := func(name, nodeName string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	withOwner := func(pod *corev1.Pod, uid types.UID) *corev1.Pod {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: string(uid), UID: uid, Controller: &controller}}
+		return pod
+	}
+	perOwnerPP := makePP(v1alpha1.ActionMust, intstr.FromString("50%"))
+	perOwnerPP.Spec.Policy.Scope = v1alpha1.ScopePerOwner
+	nodeGroupPP := makePP(v1alpha1.ActionMust, intstr.IntOrString{})
+	nodeGroupPP.Spec.NodeSelector = nil
+	nodeGroupPP.Spec.Policy.TargetSize = nil
+	for _, name := range []string{"want", "unwant"} {
+		targetSize := intstr.FromString("50%")
+		nodeGroupPP.Spec.Policy.Groups = append(nodeGroupPP.Spec.Policy.Groups, v1alpha1.NodeGroup{
+			Name:         name,
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"node": name}},
+			TargetSize:   &targetSize,
+		})
+	}
+
+	tests := []struct {
+		name                    string
+		pp                      *v1alpha1.PlacementPolicy
+		podList                 []*corev1.Pod
+		wantMatchedPods         int32
+		wantPodsOnMatchingNodes int32
+		wantTargetPods          int32
+		wantGroups              []v1alpha1.NodeGroupStatus
+		wantSatisfied           metav1.ConditionStatus
+	}{
+		{
+			name:          "no pods",
+			pp:            makePP(v1alpha1.ActionMust, intstr.FromString("40%")),
+			podList:       []*corev1.Pod{},
+			wantSatisfied: metav1.ConditionTrue,
+		},
+		{
+			name: "must policy satisfied",
+			pp:   makePP(v1alpha1.ActionMust, intstr.FromString("40%")),
+			podList: []*corev1.Pod{
+				makePod("pod1", "node1", corev1.PodRunning),
+				makePod("pod2", "node2", corev1.PodRunning),
+				makePod("pod3", "node3", corev1.PodRunning),
+				makePod("pod4", "node3", corev1.PodRunning),
+				makePod("pod5", "node3", corev1.PodRunning),
+			},
+			wantMatchedPods:         5,
+			wantPodsOnMatchingNodes: 2,
+			wantTargetPods:          2,
+			wantSatisfied:           metav1.ConditionTrue,
+		},
+		{
+			name: "must policy drifted",
+			pp:   makePP(v1alpha1.ActionMust, intstr.FromString("40%")),
+			podList: []*corev1.Pod{
+				makePod("pod1", "node1", corev1.PodRunning),
+				makePod("pod2", "node3", corev1.PodRunning),
+				makePod("pod3", "node3", corev1.PodRunning),
+				makePod("pod4", "node3", corev1.PodRunning),
+				makePod("pod5", "", corev1.PodPending),
+			},
+			wantMatchedPods:         5,
+			wantPodsOnMatchingNodes: 1,
+			wantTargetPods:          2,
+			wantSatisfied:           metav1.ConditionFalse,
+		},
+		{
+			name: "mustnot policy uses the inverse of target size",
+			pp:   makePP(v1alpha1.ActionMustNot, intstr.FromInt(1)),
+			podList: []*corev1.Pod{
+				makePod("pod1", "node1", corev1.PodRunning),
+				makePod("pod2", "node2", corev1.PodRunning),
+				makePod("pod3", "node3", corev1.PodRunning),
+			},
+			wantMatchedPods:         3,
+			wantPodsOnMatchingNodes: 2,
+			wantTargetPods:          2,
+			wantSatisfied:           metav1.ConditionTrue,
+		},
+		{
+			name: "completed pods are not counted",
+			pp:   makePP(v1alpha1.ActionMust, intstr.FromString("50%")),
+			podList: []*corev1.Pod{
+				makePod("pod1", "node1", corev1.PodRunning),
+				makePod("pod2", "node3", corev1.PodRunning),
+				makePod("pod3", "node1", corev1.PodSucceeded),
+				makePod("pod4", "node1", corev1.PodFailed),
+			},
+			wantMatchedPods:         2,
+			wantPodsOnMatchingNodes: 1,
+			wantTargetPods:          1,
+			wantSatisfied:           metav1.ConditionTrue,
+		},
+		{
+			name: "per owner policy sums the target of each owner",
+			pp:   perOwnerPP,
+			podList: []*corev1.Pod{
+				withOwner(makePod("pod1", "node1", corev1.PodRunning), "rs1"),
+				withOwner(makePod("pod2", "node3", corev1.PodRunning), "rs1"),
+				withOwner(makePod("pod3", "node3", corev1.PodRunning), "rs1"),
+				withOwner(makePod("pod4", "node2", corev1.PodRunning), "rs2"),
+				withOwner(makePod("pod5", "node3", corev1.PodRunning), "rs2"),
+				withOwner(makePod("pod6", "node3", corev1.PodRunning), "rs2"),
+			},
+			wantMatchedPods:         6,
+			wantPodsOnMatchingNodes: 2,
+			wantTargetPods:          2,
+			wantSatisfied:           metav1.ConditionTrue,
+		},
+		{
+			name: "per owner policy drifted when an owner is off its target",
+			pp:   perOwnerPP,
+			podList: []*corev1.Pod{
+				// rs1 is over its target and rs2 equally under it
+				withOwner(makePod("pod1", "node1", corev1.PodRunning), "rs1"),
+				withOwner(makePod("pod2", "node2", corev1.PodRunning), "rs1"),
+				withOwner(makePod("pod3", "node3", corev1.PodRunning), "rs2"),
+				withOwner(makePod("pod4", "node3", corev1.PodRunning), "rs2"),
+			},
+			wantMatchedPods:         4,
+			wantPodsOnMatchingNodes: 2,
+			wantTargetPods:          2,
+			wantSatisfied:           metav1.ConditionFalse,
+		},
+		{
+			name: "node group policy satisfied within one pod of the targets",
+			pp:   nodeGroupPP,
+			podList: []*corev1.Pod{
+				makePod("pod1", "node1", corev1.PodRunning),
+				makePod("pod2", "node2", corev1.PodRunning),
+				makePod("pod3", "node3", corev1.PodRunning),
+			},
+			wantMatchedPods:         3,
+			wantPodsOnMatchingNodes: 3,
+			wantTargetPods:          2,
+			wantGroups: []v1alpha1.NodeGroupStatus{
+				{Name: "want", PodsOnMatchingNodes: 2, TargetPods: 1},
+				{Name: "unwant", PodsOnMatchingNodes: 1, TargetPods: 1},
+			},
+			wantSatisfied: metav1.ConditionTrue,
+		},
+		{
+			name: "node group policy drifted",
+			pp:   nodeGroupPP,
+			podList: []*corev1.Pod{
+				makePod("pod1", "node1", corev1.PodRunning),
+				makePod("pod2", "node2", corev1.PodRunning),
+				makePod("pod3", "node2", corev1.PodRunning),
+				makePod("pod4", "", corev1.PodPending),
+			},
+			wantMatchedPods:         4,
+			wantPodsOnMatchingNodes: 3,
+			wantTargetPods:          4,
+			wantGroups: []v1alpha1.NodeGroupStatus{
+				{Name: "want", PodsOnMatchingNodes: 3, TargetPods: 2},
+				{Name: "unwant", PodsOnMatchingNodes: 0, TargetPods: 2},
+			},
+			wantSatisfied: metav1.ConditionFalse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the pods are counted as by the placement policy manager in sync
+			got, err := computeStatus(tt.pp, core.GetCountedPods(tt.podList), nodeList)
+			if err != nil {
+				t.Fatalf("computeStatus() error = %v", err)
+			}
+			if got.ObservedGeneration != tt.pp.Generation {
+				t.Errorf("computeStatus() observedGeneration = %d, want %d", got.ObservedGeneration, tt.pp.Generation)
+			}
+			if got.MatchedPods != tt.wantMatchedPods {
+				t.Errorf("computeStatus() matchedPods = %d, want %d", got.MatchedPods, tt.wantMatchedPods)
+			}
+			if got.PodsOnMatchingNodes != tt.wantPodsOnMatchingNodes {
+				t.Errorf("computeStatus() podsOnMatchingNodes = %d, want %d", got.PodsOnMatchingNodes, tt.wantPodsOnMatchingNodes)
+			}
+			if got.TargetPods != tt.wantTargetPods {
+				t.Errorf("computeStatus() targetPods = %d, want %d", got.TargetPods, tt.wantTargetPods)
+			}
+			if !reflect.DeepEqual(got.Groups, tt.wantGroups) {
+				t.Errorf("computeStatus() groups = %+v, want %+v", got.Groups, tt.wantGroups)
+			}
+			condition := meta.FindStatusCondition(got.Conditions, v1alpha1.PlacementPolicyConditionSatisfied)
+			if condition == nil {
+				t.Fatalf("computeStatus() condition %s not found", v1alpha1.PlacementPolicyConditionSatisfied)
+			}
+			if condition.Status != tt.wantSatisfied {
+				t.Errorf("computeStatus() condition status = %s, want %s", condition.Status, tt.wantSatisfied)
+			}
+		})
+	}
+}
+
+func TestNamespaceLabelsChanged(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}}
+	client := fake.NewSimpleClientset(namespace)
+	ppClient := ppfake.NewSimpleClientset(
+		&v1alpha1.PlacementPolicy{ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "default"}},
+		&v1alpha1.PlacementPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "selector", Namespace: "default"},
+			Spec: v1alpha1.PlacementPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}},
+			},
+		},
+	)
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	ppInformerFactory := ppinformers.NewSharedInformerFactory(ppClient, 0)
+	c := NewController(
+		ppClient,
+		ppInformerFactory.Placementpolicy().V1alpha1().PlacementPolicies(),
+		informerFactory.Core().V1().Pods(),
+		informerFactory.Core().V1().Nodes(),
+		informerFactory.Core().V1().Namespaces(),
+		nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ppInformerFactory.Start(ctx.Done())
+	ppInformerFactory.WaitForCacheSync(ctx.Done())
+	informerFactory.Start(ctx.Done())
+	informerFactory.WaitForCacheSync(ctx.Done())
+
+	// getKeys waits for the expected number of keys to be enqueued and drains the queue
+	getKeys := func(step string, want int) map[string]bool {
+		t.Helper()
+		if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			return c.queue.Len() >= want, nil
+		}); err != nil {
+			t.Fatalf("%s: placement policies not enqueued: %v", step, err)
+		}
+		keys := make(map[string]bool)
+		for c.queue.Len() > 0 {
+			key, _ := c.queue.Get()
+			keys[key.(string)] = true
+			c.queue.Forget(key)
+			c.queue.Done(key)
+		}
+		return keys
+	}
+	getKeys("informers started", 2)
+
+	namespace = namespace.DeepCopy()
+	namespace.Labels["team"] = "b"
+	if _, err := client.CoreV1().Namespaces().Update(ctx, namespace, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to update namespace: %v", err)
+	}
+	want := map[string]bool{"default/selector": true}
+	if got := getKeys("namespace labels changed", 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("enqueued placement policies = %v, want %v", got, want)
+	}
+}
+
+func TestDeletedPlacementPolicyGauges(t *testing.T) {
+	metrics.RegisterPolicyStatus()
+	pp := &v1alpha1.PlacementPolicy{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "default"}}
+	ppClient := ppfake.NewSimpleClientset(pp)
+	informerFactory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	ppInformerFactory := ppinformers.NewSharedInformerFactory(ppClient, 0)
+	c := NewController(
+		ppClient,
+		ppInformerFactory.Placementpolicy().V1alpha1().PlacementPolicies(),
+		informerFactory.Core().V1().Pods(),
+		informerFactory.Core().V1().Nodes(),
+		informerFactory.Core().V1().Namespaces(),
+		nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	informerFactory.Start(ctx.Done())
+	ppInformerFactory.Start(ctx.Done())
+	informerFactory.WaitForCacheSync(ctx.Done())
+	ppInformerFactory.WaitForCacheSync(ctx.Done())
+
+	// waitForKey waits for the key of the placement policy to be enqueued
+	waitForKey := func(step string) {
+		t.Helper()
+		if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			return c.queue.Len() > 0, nil
+		}); err != nil {
+			t.Fatalf("%s: placement policy not enqueued: %v", step, err)
+		}
+	}
+	waitForKey("placement policy added")
+	key, _ := c.queue.Get()
+	c.queue.Forget(key)
+	c.queue.Done(key)
+
+	metrics.SetPolicyStatus(pp.Namespace, pp.Name, v1alpha1.PlacementPolicyStatus{MatchedPods: 3, PodsOnMatchingNodes: 1, TargetPods: 2})
+	if !hasPolicyGauges(t, pp) {
+		t.Fatalf("gauges of the placement policy not reported")
+	}
+
+	if err := ppClient.PlacementpolicyV1alpha1().PlacementPolicies(pp.Namespace).Delete(ctx, pp.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("failed to delete placement policy: %v", err)
+	}
+	waitForKey("placement policy deleted")
+	c.processNextItem(ctx)
+	if hasPolicyGauges(t, pp) {
+		t.Errorf("gauges of the deleted placement policy still reported")
+	}
+}
+
+// hasPolicyGauges returns true if any status gauge of the placement policy is reported
+func hasPolicyGauges(t *testing.T, pp *v1alpha1.PlacementPolicy) bool {
+	t.Helper()
+	families, err := legacyregistry.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, family := range families {
+		switch family.GetName() {
+		case "placement_policy_matched_pods", "placement_policy_pods_on_matching_nodes", "placement_policy_target_pods":
+		default:
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["namespace"] == pp.Namespace && labels["placement_policy"] == pp.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
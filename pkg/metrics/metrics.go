@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	// PlacementPolicySubsystem is the subsystem name of the placement policy metrics
+	PlacementPolicySubsystem = "placement_policy"
+
+	namespaceLabel       = "namespace"
+	placementPolicyLabel = "placement_policy"
+)
+
+var (
+	// PodsEvaluated is the number of scheduling attempts of pods matching a placement policy.
+	PodsEvaluated = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      PlacementPolicySubsystem,
+			Name:           "pods_evaluated_total",
+			Help:           "Number of scheduling attempts of pods matching the placement policy.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{namespaceLabel, placementPolicyLabel})
+
+	// FilterRejections is the number of nodes filtered out by a Strict placement policy.
+	FilterRejections = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      PlacementPolicySubsystem,
+			Name:           "filter_rejections_total",
+			Help:           "Number of nodes filtered out by the Strict placement policy.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{namespaceLabel, placementPolicyLabel})
+
+	// AnnotationFailures is the number of failed writes of the node preference annotation on pods.
+	AnnotationFailures = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      PlacementPolicySubsystem,
+			Name:           "annotation_failures_total",
+			Help:           "Number of failed writes of the node preference annotation on pods matching the placement policy.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{namespaceLabel, placementPolicyLabel})
+
+	// PreemptionVictims is the number of pods preempted for the pods of a Strict placement policy.
+	PreemptionVictims = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      PlacementPolicySubsystem,
+			Name:           "preemption_victims_total",
+			Help:           "Number of pods preempted for the pods matching the Strict placement policy.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{namespaceLabel, placementPolicyLabel})
+
+	// CounterMismatches is the number of times the placement counters of a placement policy didn't
+	// match the full recount of its pods.
+	CounterMismatches = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      PlacementPolicySubsystem,
+			Name:           "counter_mismatches_total",
+			Help:           "Number of times the incremental pod counters of the placement policy didn't match a full recount of its pods.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{namespaceLabel, placementPolicyLabel})
+
+	// LookupLatency is the latency of looking up the placement policies matching a pod.
+	LookupLatency = metrics.NewHistogram(
+		&metrics.HistogramOpts{
+			Subsystem:      PlacementPolicySubsystem,
+			Name:           "lookup_duration_seconds",
+			Help:           "Latency in seconds of looking up the placement policies matching a pod.",
+			Buckets:        metrics.ExponentialBuckets(0.0001, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		})
+
+	// MatchedPods is the number of pods selected by a placement policy, as reported in its status.
+	MatchedPods = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      PlacementPolicySubsystem,
+			Name:           "matched_pods",
+			Help:           "Number of pods selected by the placement policy.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{namespaceLabel, placementPolicyLabel})
+
+	// PodsOnMatchingNodes is the number of matched pods running on nodes selected by a placement policy.
+	PodsOnMatchingNodes = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      PlacementPolicySubsystem,
+			Name:           "pods_on_matching_nodes",
+			Help:           "Number of pods selected by the placement policy running on nodes selected by the placement policy.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{namespaceLabel, placementPolicyLabel})
+
+	// TargetPods is the number of matched pods that should be running on nodes selected by a placement policy.
+	TargetPods = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      PlacementPolicySubsystem,
+			Name:           "target_pods",
+			Help:           "Number of pods selected by the placement policy that should be running on nodes selected by the placement policy.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{namespaceLabel, placementPolicyLabel})
+
+	metricsList = []metrics.Registerable{
+		PodsEvaluated,
+		FilterRejections,
+		AnnotationFailures,
+		PreemptionVictims,
+		CounterMismatches,
+		LookupLatency,
+	}
+
+	policyStatusMetricsList = []metrics.Registerable{
+		MatchedPods,
+		PodsOnMatchingNodes,
+		TargetPods,
+	}
+)
+
+var (
+	registerMetrics             sync.Once
+	registerPolicyStatusMetrics sync.Once
+)
+
+// Register registers the metrics of the scheduler plugin in the legacy registry, which
+// is served by the scheduler on its /metrics endpoint.
+func Register() {
+	registerMetrics.Do(func() {
+		for _, metric := range metricsList {
+			legacyregistry.MustRegister(metric)
+		}
+	})
+}
+
+// RegisterPolicyStatus registers the gauges of the status of the placement policies in the
+// legacy registry, which is served by the controller manager keeping the status on its
+// /metrics/placement-policy endpoint.
+func RegisterPolicyStatus() {
+	registerPolicyStatusMetrics.Do(func() {
+		for _, metric := range policyStatusMetricsList {
+			legacyregistry.MustRegister(metric)
+		}
+	})
+}
+
+// SetPolicyStatus sets the pod count gauges of the placement policy from its status.
+func SetPolicyStatus(namespace, name string, status v1alpha1.PlacementPolicyStatus) {
+	MatchedPods.WithLabelValues(namespace, name).Set(float64(status.MatchedPods))
+	PodsOnMatchingNodes.WithLabelValues(namespace, name).Set(float64(status.PodsOnMatchingNodes))
+	TargetPods.WithLabelValues(namespace, name).Set(float64(status.TargetPods))
+}
+
+// DeletePolicyStatus removes the pod count gauges of a placement policy that no longer exists.
+func DeletePolicyStatus(namespace, name string) {
+	MatchedPods.DeleteLabelValues(namespace, name)
+	PodsOnMatchingNodes.DeleteLabelValues(namespace, name)
+	TargetPods.DeleteLabelValues(namespace, name)
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// AssumedPlacement is the node group a pod was reserved on by the plugin
+// while the pod is not bound to a node yet.
+type AssumedPlacement struct {
+	// PolicyName is the name of the placement policy applied to the pod
+	PolicyName string
+	// NodeName is the name of the node the pod was reserved on
+	NodeName string
+	// NodeWithMatchingLabels is true if the pod was reserved on a node with
+	// labels matching the node selector of the placement policy
+	NodeWithMatchingLabels bool
+}
+
+// AssumedPlacementCache is an in-memory cache of the placements assumed for
+// pods in flight, keyed by pod UID. Placements are recorded when the pod is
+// reserved on a node and removed when the reservation is rolled back, or
+// when the pod is bound or deleted.
+type AssumedPlacementCache struct {
+	sync.RWMutex
+	placements map[types.UID]AssumedPlacement
+}
+
+// NewAssumedPlacementCache returns an empty assumed placement cache.
+func NewAssumedPlacementCache() *AssumedPlacementCache {
+	return &AssumedPlacementCache{
+		placements: make(map[types.UID]AssumedPlacement),
+	}
+}
+
+// Assume records the placement for the pod, replacing any previous one.
+func (c *AssumedPlacementCache) Assume(uid types.UID, placement AssumedPlacement) {
+	c.Lock()
+	defer c.Unlock()
+	c.placements[uid] = placement
+}
+
+// Get returns the placement assumed for the pod, if any.
+func (c *AssumedPlacementCache) Get(uid types.UID) (AssumedPlacement, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	placement, ok := c.placements[uid]
+	return placement, ok
+}
+
+// Forget removes the placement assumed for the pod.
+func (c *AssumedPlacementCache) Forget(uid types.UID) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.placements, uid)
+}
+
+// Len returns the number of pods with an assumed placement.
+func (c *AssumedPlacementCache) Len() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.placements)
+}
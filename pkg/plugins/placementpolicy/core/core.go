@@ -2,17 +2,19 @@ package core
 
 import (
 	"context"
-	"sort"
+	"encoding/json"
+	"fmt"
+	"strconv"
 
 	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
 	ppclientset "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/clientset/versioned"
 	ppinformers "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/informers/externalversions/apis/v1alpha1"
 	pplisters "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/listers/apis/v1alpha1"
-	"github.com/Azure/placement-policy-scheduler-plugins/pkg/utils"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
@@ -21,8 +23,9 @@ import (
 // Manager defines the interfaces for PlacementPolicy management.
 type Manager interface {
 	GetPlacementPolicyForPod(context.Context, *corev1.Pod) (*v1alpha1.PlacementPolicy, error)
-	GetPodsWithLabels(context.Context, map[string]string) ([]*corev1.Pod, error)
-	AnnotatePod(context.Context, *corev1.Pod, *v1alpha1.PlacementPolicy, bool) (*corev1.Pod, error)
+	GetPlacementPoliciesForPod(context.Context, *corev1.Pod) ([]*v1alpha1.PlacementPolicy, error)
+	GetPodsForPlacementPolicy(context.Context, *v1alpha1.PlacementPolicy) ([]*corev1.Pod, error)
+	AnnotatePod(context.Context, *corev1.Pod, *v1alpha1.PlacementPolicy, bool) error
 	GetPlacementPolicy(context.Context, string, string) (*v1alpha1.PlacementPolicy, error)
 }
 
@@ -33,10 +36,18 @@ type PlacementPolicyManager struct {
 	ppClient ppclientset.Interface
 	// podLister is pod lister
 	podLister corelisters.PodLister
+	// namespaceLister is namespace lister
+	namespaceLister corelisters.NamespaceLister
 	// snapshotSharedLister is pod shared list
 	snapshotSharedLister framework.SharedLister
 	// ppLister is placementPolicy lister
 	ppLister pplisters.PlacementPolicyLister
+	// cppLister is clusterPlacementPolicy lister
+	cppLister pplisters.ClusterPlacementPolicyLister
+	// defaultEnforcementMode is the enforcement mode of the policies that don't specify one
+	defaultEnforcementMode v1alpha1.EnforcementMode
+	// index is the index of the placement policies matching the pods
+	index *policyIndex
 }
 
 func NewPlacementPolicyManager(
@@ -44,67 +55,219 @@ func NewPlacementPolicyManager(
 	ppClient ppclientset.Interface,
 	snapshotSharedLister framework.SharedLister,
 	ppInformer ppinformers.PlacementPolicyInformer,
-	podLister corelisters.PodLister) *PlacementPolicyManager {
-	return &PlacementPolicyManager{
-		client:               client,
-		ppClient:             ppClient,
-		snapshotSharedLister: snapshotSharedLister,
-		ppLister:             ppInformer.Lister(),
-		podLister:            podLister,
+	cppInformer ppinformers.ClusterPlacementPolicyInformer,
+	podLister corelisters.PodLister,
+	namespaceLister corelisters.NamespaceLister,
+	defaultEnforcementMode v1alpha1.EnforcementMode) *PlacementPolicyManager {
+	m := &PlacementPolicyManager{
+		client:                 client,
+		ppClient:               ppClient,
+		snapshotSharedLister:   snapshotSharedLister,
+		ppLister:               ppInformer.Lister(),
+		cppLister:              cppInformer.Lister(),
+		podLister:              podLister,
+		namespaceLister:        namespaceLister,
+		defaultEnforcementMode: defaultEnforcementMode,
 	}
+	m.index = newPolicyIndex(m.ppLister, m.cppLister, namespaceLister, m.withDefaults)
+	// the index is invalidated when the placement policies change, the event handlers
+	// have to be registered before the informers are started
+	ppInformer.Informer().AddEventHandler(m.index.placementPolicyHandler())
+	cppInformer.Informer().AddEventHandler(m.index.clusterPlacementPolicyHandler())
+	return m
 }
 
-// GetPlacementPolicyForPod returns the placement policy for the given pod
+// GetPlacementPolicyForPod returns the placement policy for the given pod. The placement
+// policies in the namespace of the pod take precedence over the cluster placement policies.
 func (m *PlacementPolicyManager) GetPlacementPolicyForPod(ctx context.Context, pod *corev1.Pod) (*v1alpha1.PlacementPolicy, error) {
-	ppList, err := m.ppLister.PlacementPolicies(pod.Namespace).List(labels.Everything())
-	if err != nil {
+	ppList, err := m.GetPlacementPoliciesForPod(ctx, pod)
+	if err != nil || len(ppList) == 0 {
 		return nil, err
 	}
-	// filter the placement policy list based on the pod's labels
-	ppList = m.filterPlacementPolicyList(ppList, pod)
-	if len(ppList) == 0 {
-		return nil, nil
+	return ppList[0], nil
+}
+
+// GetPlacementPoliciesForPod returns the placement policies matching the given pod, in the
+// order of precedence. The first one is the placement policy applied to the pod. The policies
+// are looked up in the policy index and must not be modified.
+func (m *PlacementPolicyManager) GetPlacementPoliciesForPod(ctx context.Context, pod *corev1.Pod) ([]*v1alpha1.PlacementPolicy, error) {
+	ppList, err := m.index.getPlacementPoliciesForPod(pod)
+	if err != nil || len(ppList) == 0 {
+		return nil, err
 	}
-	if len(ppList) > 1 {
-		// if there are multiple placement policies, sort them by weight and return the first one
-		sort.Sort(sort.Reverse(ByWeight(ppList)))
+	return ppList, nil
+}
+
+// GetPodsForPlacementPolicy returns the pods counted by the placement policy: the pods
+// matching the pod selector in the namespace of the policy and, if the policy has a
+// namespace selector, in the namespaces matching the namespace selector, that are not
+// completed
+func (m *PlacementPolicyManager) GetPodsForPlacementPolicy(ctx context.Context, pp *v1alpha1.PlacementPolicy) ([]*corev1.Pod, error) {
+	podSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.PodSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pod selector: %w", err)
+	}
+	if pp.Spec.NamespaceSelector == nil {
+		podList, err := m.podLister.Pods(pp.Namespace).List(podSelector)
+		if err != nil {
+			return nil, err
+		}
+		return GetCountedPods(podList), nil
 	}
 
-	return ppList[0], nil
+	namespaceSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse namespace selector: %w", err)
+	}
+	namespaceList, err := m.namespaceLister.List(namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	// the pods in the namespace of the policy are counted even if the namespace
+	// doesn't match the namespace selector
+	podList, err := m.podLister.Pods(pp.Namespace).List(podSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, namespace := range namespaceList {
+		if namespace.Name == pp.Namespace {
+			continue
+		}
+		pods, err := m.podLister.Pods(namespace.Name).List(podSelector)
+		if err != nil {
+			return nil, err
+		}
+		podList = append(podList, pods...)
+	}
+	return GetCountedPods(podList), nil
 }
 
-func (m *PlacementPolicyManager) GetPodsWithLabels(ctx context.Context, podLabels map[string]string) ([]*corev1.Pod, error) {
-	return m.podLister.List(labels.Set(podLabels).AsSelector())
+// IsCountedPod returns true if the pod is counted by the placement policies. Completed pods
+// no longer occupy a node, so they are counted neither by the scheduler plugin nor by the
+// status, the rebalancer and the explanation of the placement policies.
+func IsCountedPod(pod *corev1.Pod) bool {
+	return pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed
 }
 
-// AnnotatePod annotates the pod with the placement policy.
-func (m *PlacementPolicyManager) AnnotatePod(ctx context.Context, pod *corev1.Pod, pp *v1alpha1.PlacementPolicy, preferredNodeWithMatchingLabels bool) (*corev1.Pod, error) {
-	annotations := map[string]string{}
-	if pod.Annotations != nil {
-		annotations = pod.Annotations
+// GetCountedPods returns the pods of the list counted by the placement policies
+func GetCountedPods(podList []*corev1.Pod) []*corev1.Pod {
+	counted := make([]*corev1.Pod, 0, len(podList))
+	for _, pod := range podList {
+		if IsCountedPod(pod) {
+			counted = append(counted, pod)
+		}
 	}
+	return counted
+}
 
-	preference := "false"
-	if preferredNodeWithMatchingLabels {
-		preference = "true"
+// AnnotatePod annotates the pod with the placement policy and the node preference.
+// A strategic merge patch is used so the pod object in the informer cache is not
+// mutated and the write doesn't conflict with concurrent updates of the pod.
+func (m *PlacementPolicyManager) AnnotatePod(ctx context.Context, pod *corev1.Pod, pp *v1alpha1.PlacementPolicy, preferredNodeWithMatchingLabels bool) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				v1alpha1.PlacementPolicyAnnotationKey:           pp.Name,
+				v1alpha1.PlacementPolicyPreferenceAnnotationKey: strconv.FormatBool(preferredNodeWithMatchingLabels),
+			},
+		},
+	})
+	if err != nil {
+		return err
 	}
-	annotations[v1alpha1.PlacementPolicyAnnotationKey] = pp.Name
-	annotations[v1alpha1.PlacementPolicyPreferenceAnnotationKey] = preference
-	pod.Annotations = annotations
-	return m.client.CoreV1().Pods(pod.Namespace).Update(ctx, pod, metav1.UpdateOptions{})
+	_, err = m.client.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	return err
 }
 
 func (m *PlacementPolicyManager) GetPlacementPolicy(ctx context.Context, namespace, name string) (*v1alpha1.PlacementPolicy, error) {
-	return m.ppLister.PlacementPolicies(namespace).Get(name)
+	pp, err := m.ppLister.PlacementPolicies(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return m.withDefaults(pp), nil
+}
+
+// withDefaults returns a copy of the placement policy with the defaults applied,
+// so policies stored before the mutating webhook was installed behave the same
+// as the ones that went through it. The webhook leaves the enforcement mode unset,
+// so the configured default applies. The object from the lister is left untouched.
+func (m *PlacementPolicyManager) withDefaults(pp *v1alpha1.PlacementPolicy) *v1alpha1.PlacementPolicy {
+	pp = pp.DeepCopy()
+	if pp.Spec.EnforcementMode == "" {
+		pp.Spec.EnforcementMode = m.defaultEnforcementMode
+	}
+	if pp.Spec.EnforcementMode == "" {
+		pp.Spec.EnforcementMode = v1alpha1.DefaultEnforcementMode
+	}
+	pp.Default()
+	return pp
+}
+
+// GetPodsInScope returns the pods out of podList the target size of the placement policy
+// is computed over when scheduling the given pod
+func GetPodsInScope(pp *v1alpha1.PlacementPolicy, podList []*corev1.Pod, pod *corev1.Pod) []*corev1.Pod {
+	if pp.Spec.Policy.Scope != v1alpha1.ScopePerOwner {
+		return podList
+	}
+	return GroupPodsByOwner(podList)[getOwnerUID(pod)]
+}
+
+// GroupPodsByOwner groups the pods by the UID of their controlling owner. The pods
+// without a controlling owner are grouped together under the empty UID.
+func GroupPodsByOwner(podList []*corev1.Pod) map[types.UID][]*corev1.Pod {
+	groups := make(map[types.UID][]*corev1.Pod)
+	for _, pod := range podList {
+		uid := getOwnerUID(pod)
+		groups[uid] = append(groups[uid], pod)
+	}
+	return groups
 }
 
-func (m *PlacementPolicyManager) filterPlacementPolicyList(ppList []*v1alpha1.PlacementPolicy, pod *corev1.Pod) []*v1alpha1.PlacementPolicy {
-	var filteredPPList []*v1alpha1.PlacementPolicy
-	for _, pp := range ppList {
-		labels := pp.Spec.PodSelector.MatchLabels
-		if utils.HasMatchingLabels(pod.Labels, labels) {
-			filteredPPList = append(filteredPPList, pp)
+// getOwnerUID returns the UID of the controlling owner of the pod, or an empty UID
+// if the pod doesn't have one
+func getOwnerUID(pod *corev1.Pod) types.UID {
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		return owner.UID
+	}
+	return ""
+}
+
+// GetTargetSize returns the number of pods, out of totalPods, that the placement policy
+// expects to be placed on the nodes with labels matching the node selector
+func GetTargetSize(pp *v1alpha1.PlacementPolicy, totalPods int) (int, error) {
+	policy := pp.Spec.Policy
+	targetSize, err := getScaledTargetSize(policy.TargetSize, policy.Rounding, totalPods)
+	if err != nil {
+		return 0, err
+	}
+	// the bounds apply to the target size as written in the policy, before it's inverted for mustnot.
+	// The lower bound can't exceed the pods the target size is computed over.
+	if policy.MinSize != nil && targetSize < int(*policy.MinSize) {
+		targetSize = int(*policy.MinSize)
+		if targetSize > totalPods {
+			targetSize = totalPods
 		}
 	}
-	return filteredPPList
+	if policy.MaxSize != nil && targetSize > int(*policy.MaxSize) {
+		targetSize = int(*policy.MaxSize)
+	}
+	// if the action is mustnot, we'll use the inverse of the target size against total pods
+	// to compute number of pods on nodes with matching labels
+	if pp.Spec.Policy.Action == v1alpha1.ActionMustNot {
+		targetSize = totalPods - targetSize
+	}
+	return targetSize, nil
+}
+
+// getScaledTargetSize returns the number of pods of the target size out of totalPods, a percentage
+// being rounded according to the rounding mode
+func getScaledTargetSize(targetSize *intstr.IntOrString, rounding v1alpha1.RoundingMode, totalPods int) (int, error) {
+	if targetSize == nil || targetSize.Type != intstr.String || rounding != v1alpha1.RoundingModeNearest {
+		return intstr.GetScaledValueFromIntOrPercent(targetSize, totalPods, rounding == v1alpha1.RoundingModeUp)
+	}
+	percent, err := intstr.GetScaledValueFromIntOrPercent(targetSize, 100, false)
+	if err != nil {
+		return 0, err
+	}
+	return (percent*totalPods + 50) / 100, nil
 }
@@ -0,0 +1,436 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	pplisters "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/listers/apis/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestGetPodsForPlacementPolicy(t *testing.T) {
+	namespaces := []*corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-a-dev", Labels: map[string]string{"team": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"team": "b"}}},
+	}
+	pods := []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "team-a", Labels: map[string]string{"app": "nginx"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "team-a", Labels: map[string]string{"app": "redis"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod3", Namespace: "team-a-dev", Labels: map[string]string{"app": "nginx"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod4", Namespace: "team-b", Labels: map[string]string{"app": "nginx"}}},
+		// completed pods are never counted
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod5", Namespace: "team-a", Labels: map[string]string{"app": "nginx"}}, Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod6", Namespace: "team-b", Labels: map[string]string{"app": "nginx"}}, Status: corev1.PodStatus{Phase: corev1.PodFailed}},
+	}
+	m := newTestPlacementPolicyManager(t, namespaces, pods, nil, nil)
+
+	tests := []struct {
+		name              string
+		namespaceSelector *metav1.LabelSelector
+		want              []string
+	}{
+		{
+			name: "pods in the policy namespace only",
+			want: []string{"pod1"},
+		},
+		{
+			name:              "pods in namespaces matching the namespace selector",
+			namespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+			want:              []string{"pod1", "pod3"},
+		},
+		{
+			name:              "pods in the policy namespace counted when not matching the namespace selector",
+			namespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}},
+			want:              []string{"pod1", "pod4"},
+		},
+		{
+			name:              "pods in all namespaces",
+			namespaceSelector: &metav1.LabelSelector{},
+			want:              []string{"pod1", "pod3", "pod4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp := &v1alpha1.PlacementPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "team-a"},
+				Spec: v1alpha1.PlacementPolicySpec{
+					PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+					NamespaceSelector: tt.namespaceSelector,
+				},
+			}
+			podList, err := m.GetPodsForPlacementPolicy(context.Background(), pp)
+			if err != nil {
+				t.Fatalf("GetPodsForPlacementPolicy() error = %v", err)
+			}
+			got := make([]string, 0, len(podList))
+			for _, pod := range podList {
+				got = append(got, pod.Name)
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetPodsForPlacementPolicy() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("GetPodsForPlacementPolicy() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetPlacementPolicyForPod(t *testing.T) {
+	namespaces := []*corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"team": "b"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-c", Labels: map[string]string{"team": "c"}}},
+	}
+	newSpec := func(app string) v1alpha1.PlacementPolicySpec {
+		return v1alpha1.PlacementPolicySpec{
+			PodSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+			Policy:       &v1alpha1.Policy{},
+		}
+	}
+	ppList := []*v1alpha1.PlacementPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "team-a"}, Spec: newSpec("nginx")},
+	}
+	cppList := []*v1alpha1.ClusterPlacementPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cpp-all"},
+			Spec: v1alpha1.ClusterPlacementPolicySpec{
+				PodSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+				NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+				Policy:       &v1alpha1.Policy{},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cpp-team-b"},
+			Spec: v1alpha1.ClusterPlacementPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "redis"}},
+				NodeSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+				Policy:            &v1alpha1.Policy{},
+			},
+		},
+	}
+	m := newTestPlacementPolicyManager(t, namespaces, nil, ppList, cppList)
+
+	tests := []struct {
+		name          string
+		pod           *corev1.Pod
+		wantName      string
+		wantNamespace string
+	}{
+		{
+			name:          "placement policy in the pod namespace takes precedence",
+			pod:           &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "team-a", Labels: map[string]string{"app": "nginx"}}},
+			wantName:      "pp",
+			wantNamespace: "team-a",
+		},
+		{
+			name:          "cluster placement policy without namespace selector",
+			pod:           &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "team-c", Labels: map[string]string{"app": "nginx"}}},
+			wantName:      "cpp-all",
+			wantNamespace: "team-c",
+		},
+		{
+			name:          "cluster placement policy with matching namespace selector",
+			pod:           &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "team-b", Labels: map[string]string{"app": "redis"}}},
+			wantName:      "cpp-team-b",
+			wantNamespace: "team-b",
+		},
+		{
+			name: "cluster placement policy with namespace selector not matching",
+			pod:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "team-c", Labels: map[string]string{"app": "redis"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp, err := m.GetPlacementPolicyForPod(context.Background(), tt.pod)
+			if err != nil {
+				t.Fatalf("GetPlacementPolicyForPod() error = %v", err)
+			}
+			if tt.wantName == "" {
+				if pp != nil {
+					t.Errorf("GetPlacementPolicyForPod() = %s/%s, want nil", pp.Namespace, pp.Name)
+				}
+				return
+			}
+			if pp == nil {
+				t.Fatalf("GetPlacementPolicyForPod() = nil, want %s/%s", tt.wantNamespace, tt.wantName)
+			}
+			if pp.Name != tt.wantName || pp.Namespace != tt.wantNamespace {
+				t.Errorf("GetPlacementPolicyForPod() = %s/%s, want %s/%s", pp.Namespace, pp.Name, tt.wantNamespace, tt.wantName)
+			}
+			if pp.Spec.Weight != v1alpha1.DefaultWeight {
+				t.Errorf("GetPlacementPolicyForPod() weight = %d, want default %d", pp.Spec.Weight, v1alpha1.DefaultWeight)
+			}
+		})
+	}
+}
+
+func TestGetPlacementPolicyDefaultEnforcementMode(t *testing.T) {
+	newPlacementPolicy := func(name string, mode v1alpha1.EnforcementMode) *v1alpha1.PlacementPolicy {
+		return &v1alpha1.PlacementPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1alpha1.PlacementPolicySpec{
+				EnforcementMode: mode,
+				PodSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+				NodeSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+				Policy:          &v1alpha1.Policy{},
+			},
+		}
+	}
+	ppList := []*v1alpha1.PlacementPolicy{
+		newPlacementPolicy("unset", ""),
+		newPlacementPolicy("best-effort", v1alpha1.EnforcementModeBestEffort),
+	}
+
+	tests := []struct {
+		name        string
+		defaultMode v1alpha1.EnforcementMode
+		ppName      string
+		want        v1alpha1.EnforcementMode
+	}{
+		{
+			name:   "unset enforcement mode without configured default",
+			ppName: "unset",
+			want:   v1alpha1.DefaultEnforcementMode,
+		},
+		{
+			name:        "unset enforcement mode with configured default",
+			defaultMode: v1alpha1.EnforcementModeStrict,
+			ppName:      "unset",
+			want:        v1alpha1.EnforcementModeStrict,
+		},
+		{
+			name:        "set enforcement mode is preserved",
+			defaultMode: v1alpha1.EnforcementModeStrict,
+			ppName:      "best-effort",
+			want:        v1alpha1.EnforcementModeBestEffort,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestPlacementPolicyManager(t, nil, nil, ppList, nil)
+			m.defaultEnforcementMode = tt.defaultMode
+			pp, err := m.GetPlacementPolicy(context.Background(), "default", tt.ppName)
+			if err != nil {
+				t.Fatalf("GetPlacementPolicy() error = %v", err)
+			}
+			if pp.Spec.EnforcementMode != tt.want {
+				t.Errorf("GetPlacementPolicy() enforcementMode = %s, want %s", pp.Spec.EnforcementMode, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPodsInScope(t *testing.T) {
+	controller := true
+	makePod := func(name string, owner types.UID) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		if owner != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: string(owner), UID: owner, Controller: &controller}}
+		}
+		return pod
+	}
+	podList := []*corev1.Pod{
+		makePod("pod1", "rs1"),
+		makePod("pod2", "rs1"),
+		makePod("pod3", "rs2"),
+		makePod("pod4", ""),
+		makePod("pod5", ""),
+	}
+
+	tests := []struct {
+		name  string
+		scope v1alpha1.Scope
+		pod   *corev1.Pod
+		want  []string
+	}{
+		{
+			name:  "all scope counts all the pods",
+			scope: v1alpha1.ScopeAll,
+			pod:   makePod("pod", "rs1"),
+			want:  []string{"pod1", "pod2", "pod3", "pod4", "pod5"},
+		},
+		{
+			name:  "per owner scope counts the pods of the same owner",
+			scope: v1alpha1.ScopePerOwner,
+			pod:   makePod("pod", "rs1"),
+			want:  []string{"pod1", "pod2"},
+		},
+		{
+			name:  "per owner scope groups the pods without owner",
+			scope: v1alpha1.ScopePerOwner,
+			pod:   makePod("pod", ""),
+			want:  []string{"pod4", "pod5"},
+		},
+		{
+			name:  "per owner scope with no other pods of the owner",
+			scope: v1alpha1.ScopePerOwner,
+			pod:   makePod("pod", "rs3"),
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp := &v1alpha1.PlacementPolicy{Spec: v1alpha1.PlacementPolicySpec{Policy: &v1alpha1.Policy{Scope: tt.scope}}}
+			got := []string{}
+			for _, pod := range GetPodsInScope(pp, podList, tt.pod) {
+				got = append(got, pod.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPodsInScope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTargetSize(t *testing.T) {
+	int32Ptr := func(v int32) *int32 { return &v }
+
+	tests := []struct {
+		name       string
+		targetSize intstr.IntOrString
+		action     v1alpha1.Action
+		rounding   v1alpha1.RoundingMode
+		minSize    *int32
+		maxSize    *int32
+		totalPods  int
+		want       int
+	}{
+		{
+			name:       "percentage rounded down by default",
+			targetSize: intstr.FromString("40%"),
+			totalPods:  3,
+			want:       1,
+		},
+		{
+			name:       "percentage rounded up",
+			targetSize: intstr.FromString("40%"),
+			rounding:   v1alpha1.RoundingModeUp,
+			totalPods:  3,
+			want:       2,
+		},
+		{
+			name:       "percentage rounded to nearest",
+			targetSize: intstr.FromString("40%"),
+			rounding:   v1alpha1.RoundingModeNearest,
+			totalPods:  3,
+			want:       1,
+		},
+		{
+			name:       "half rounded up to nearest",
+			targetSize: intstr.FromString("50%"),
+			rounding:   v1alpha1.RoundingModeNearest,
+			totalPods:  3,
+			want:       2,
+		},
+		{
+			name:       "absolute number is not rounded",
+			targetSize: intstr.FromInt(2),
+			rounding:   v1alpha1.RoundingModeUp,
+			totalPods:  3,
+			want:       2,
+		},
+		{
+			name:       "min size",
+			targetSize: intstr.FromString("40%"),
+			minSize:    int32Ptr(2),
+			totalPods:  3,
+			want:       2,
+		},
+		{
+			name:       "min size capped to the total pods",
+			targetSize: intstr.FromString("40%"),
+			minSize:    int32Ptr(2),
+			totalPods:  1,
+			want:       1,
+		},
+		{
+			name:       "max size",
+			targetSize: intstr.FromString("40%"),
+			maxSize:    int32Ptr(3),
+			totalPods:  10,
+			want:       3,
+		},
+		{
+			name:       "bounds applied before must not inverts the target size",
+			targetSize: intstr.FromString("40%"),
+			action:     v1alpha1.ActionMustNot,
+			minSize:    int32Ptr(2),
+			totalPods:  3,
+			want:       1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp := &v1alpha1.PlacementPolicy{Spec: v1alpha1.PlacementPolicySpec{Policy: &v1alpha1.Policy{
+				Action:     tt.action,
+				TargetSize: &tt.targetSize,
+				Rounding:   tt.rounding,
+				MinSize:    tt.minSize,
+				MaxSize:    tt.maxSize,
+			}}}
+			got, err := GetTargetSize(pp, tt.totalPods)
+			if err != nil {
+				t.Fatalf("GetTargetSize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetTargetSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestPlacementPolicyManager(t *testing.T, namespaces []*corev1.Namespace, pods []*corev1.Pod, ppList []*v1alpha1.PlacementPolicy, cppList []*v1alpha1.ClusterPlacementPolicy) *PlacementPolicyManager {
+	namespaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, namespace := range namespaces {
+		if err := namespaceIndexer.Add(namespace); err != nil {
+			t.Fatalf("failed to add namespace: %v", err)
+		}
+	}
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, pod := range pods {
+		if err := podIndexer.Add(pod); err != nil {
+			t.Fatalf("failed to add pod: %v", err)
+		}
+	}
+	ppIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, pp := range ppList {
+		if err := ppIndexer.Add(pp); err != nil {
+			t.Fatalf("failed to add placement policy: %v", err)
+		}
+	}
+	cppIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, cpp := range cppList {
+		if err := cppIndexer.Add(cpp); err != nil {
+			t.Fatalf("failed to add cluster placement policy: %v", err)
+		}
+	}
+	m := &PlacementPolicyManager{
+		podLister:       corelisters.NewPodLister(podIndexer),
+		namespaceLister: corelisters.NewNamespaceLister(namespaceIndexer),
+		ppLister:        pplisters.NewPlacementPolicyLister(ppIndexer),
+		cppLister:       pplisters.NewClusterPlacementPolicyLister(cppIndexer),
+	}
+	m.index = newPolicyIndex(m.ppLister, m.cppLister, m.namespaceLister, m.withDefaults)
+	return m
+}
@@ -0,0 +1,393 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// counterIdleTimeout is the time after which the counters no placement policy looked up are dropped
+	counterIdleTimeout = 10 * time.Minute
+	// counterCheckPeriod is the minimum time between two checks of the counters of a placement
+	// policy against a full recount of its pods
+	counterCheckPeriod = time.Minute
+)
+
+// PlacementCount is the number of pods counted by a placement policy, as maintained by the
+// placement counters.
+type PlacementCount struct {
+	// TotalPods is the number of pods matching the pod selector in the namespace of the placement policy
+	TotalPods int
+	// PodsOnNodeWithMatchingLabels is the number of those pods bound to a node with labels
+	// matching the node selector
+	PodsOnNodeWithMatchingLabels int
+	// PodsOnNodes is the number of those pods bound to a node
+	PodsOnNodes int
+	// PendingPods are the UIDs of those pods not bound to a node yet
+	PendingPods []types.UID
+}
+
+// podRecord is the part of a pod the placement counters depend on
+type podRecord struct {
+	uid       types.UID
+	namespace string
+	labels    labels.Set
+	nodeName  string
+}
+
+// selectorCounter counts the pods matching a pod selector in a namespace, and the ones bound
+// to a node matching a node selector
+type selectorCounter struct {
+	podSelector  labels.Selector
+	nodeSelector labels.Selector
+	// totalPods is the number of pods matching the pod selector
+	totalPods int
+	// podsOnNodeWithMatchingLabels is the number of those pods bound to a node matching the node selector
+	podsOnNodeWithMatchingLabels int
+	// pendingPods are those pods not bound to a node yet
+	pendingPods map[types.UID]bool
+	// lastUsed is the last time the counter was looked up
+	lastUsed time.Time
+	// lastChecked is the last time the counter was checked against a full recount
+	lastChecked time.Time
+}
+
+// PlacementCounters maintains incrementally, from the pod and node informer events, the number
+// of pods counted by each placement policy and the number of those pods on the nodes matching its
+// node selector, so the pods don't have to be listed and grouped every time a pod is scheduled.
+// The counters of a placement policy are created from the pods known to the store the first time
+// they are looked up, and dropped when no placement policy looked them up for a while. The counters
+// are keyed by the selectors of the placement policies, so a placement policy whose selectors are
+// updated is counted again from scratch.
+type PlacementCounters struct {
+	sync.Mutex
+	// pods are the pods of each namespace
+	pods map[string]map[types.UID]*podRecord
+	// podsOnNode are the pods bound to each node
+	podsOnNode map[string]map[types.UID]*podRecord
+	// nodeLabels are the labels of each node
+	nodeLabels map[string]labels.Set
+	// counters are the counters of each namespace, keyed by the selectors of the placement policies
+	counters map[string]map[string]*selectorCounter
+	// lastPruned is the last time the idle counters were dropped
+	lastPruned time.Time
+	now        func() time.Time
+}
+
+// NewPlacementCounters returns empty placement counters. The event handlers of the counters
+// have to be registered on the pod and node informers before they are started.
+func NewPlacementCounters() *PlacementCounters {
+	return &PlacementCounters{
+		pods:       make(map[string]map[types.UID]*podRecord),
+		podsOnNode: make(map[string]map[types.UID]*podRecord),
+		nodeLabels: make(map[string]labels.Set),
+		counters:   make(map[string]map[string]*selectorCounter),
+		now:        time.Now,
+	}
+}
+
+// CountersSupported returns true if the pods counted by the placement policy can be looked up in
+// the placement counters. The placement policies counting the pods of other namespaces, per owner,
+// per node group or per topology domain are counted from the full list of their pods.
+func CountersSupported(pp *v1alpha1.PlacementPolicy) bool {
+	return pp.Spec.NamespaceSelector == nil &&
+		pp.Spec.Policy.Scope != v1alpha1.ScopePerOwner &&
+		len(pp.Spec.Policy.Groups) == 0 &&
+		pp.Spec.Policy.TopologyKey == ""
+}
+
+// PodEventHandler returns the event handler counting the pods added, updated and deleted. The
+// completed pods are not counted, as if they were deleted.
+func (c *PlacementCounters) PodEventHandler() cache.ResourceEventHandler {
+	update := func(obj interface{}) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return
+		}
+		if IsCountedPod(pod) {
+			c.updatePod(newPodRecord(pod))
+		} else {
+			c.deletePod(pod.Namespace, pod.UID)
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    update,
+		UpdateFunc: func(_, newObj interface{}) { update(newObj) },
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pod, ok := obj.(*corev1.Pod); ok {
+				c.deletePod(pod.Namespace, pod.UID)
+			}
+		},
+	}
+}
+
+// NodeEventHandler returns the event handler recounting the pods of the nodes whose labels change.
+func (c *PlacementCounters) NodeEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if node, ok := obj.(*corev1.Node); ok {
+				c.updateNode(node.Name, labels.Set(node.Labels), true)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if node, ok := newObj.(*corev1.Node); ok {
+				c.updateNode(node.Name, labels.Set(node.Labels), true)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if node, ok := obj.(*corev1.Node); ok {
+				c.updateNode(node.Name, nil, false)
+			}
+		},
+	}
+}
+
+// GetCount returns the number of pods counted by the placement policy, which must be supported
+// by the counters. The second value is true if the counters of the placement policy are due to be
+// checked against a full recount of its pods.
+func (c *PlacementCounters) GetCount(pp *v1alpha1.PlacementPolicy) (PlacementCount, bool, error) {
+	c.Lock()
+	defer c.Unlock()
+	counter, err := c.getCounter(pp)
+	if err != nil {
+		return PlacementCount{}, false, err
+	}
+	now := c.now()
+	counter.lastUsed = now
+	check := now.Sub(counter.lastChecked) >= counterCheckPeriod
+	if check {
+		counter.lastChecked = now
+	}
+	count := PlacementCount{
+		TotalPods:                    counter.totalPods,
+		PodsOnNodeWithMatchingLabels: counter.podsOnNodeWithMatchingLabels,
+		PodsOnNodes:                  counter.totalPods - len(counter.pendingPods),
+		PendingPods:                  make([]types.UID, 0, len(counter.pendingPods)),
+	}
+	for uid := range counter.pendingPods {
+		count.PendingPods = append(count.PendingPods, uid)
+	}
+	return count, check, nil
+}
+
+// GetPodNodeName returns the node the pod is bound to, and whether the pod is counted by the
+// placement policy, which must be supported by the counters.
+func (c *PlacementCounters) GetPodNodeName(pp *v1alpha1.PlacementPolicy, uid types.UID) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+	pod, ok := c.pods[pp.Namespace][uid]
+	if !ok {
+		return "", false
+	}
+	counter, err := c.getCounter(pp)
+	if err != nil || !counter.podSelector.Matches(pod.labels) {
+		return "", false
+	}
+	return pod.nodeName, true
+}
+
+// Reset drops the counters of the placement policy, so they are counted again from the pods
+// known to the store the next time they are looked up.
+func (c *PlacementCounters) Reset(pp *v1alpha1.PlacementPolicy) {
+	c.Lock()
+	defer c.Unlock()
+	key, _, _, err := counterKey(pp)
+	if err != nil {
+		return
+	}
+	delete(c.counters[pp.Namespace], key)
+}
+
+// getCounter returns the counter of the selectors of the placement policy, counting the pods
+// known to the store if there is none yet. It must be called with the lock held.
+func (c *PlacementCounters) getCounter(pp *v1alpha1.PlacementPolicy) (*selectorCounter, error) {
+	c.pruneIdleCounters()
+	key, podSelector, nodeSelector, err := counterKey(pp)
+	if err != nil {
+		return nil, err
+	}
+	if counter, ok := c.counters[pp.Namespace][key]; ok {
+		return counter, nil
+	}
+
+	counter := &selectorCounter{
+		podSelector:  podSelector,
+		nodeSelector: nodeSelector,
+		pendingPods:  make(map[types.UID]bool),
+		lastChecked:  c.now(),
+	}
+	for _, pod := range c.pods[pp.Namespace] {
+		c.count(counter, pod, 1)
+	}
+	if _, ok := c.counters[pp.Namespace]; !ok {
+		c.counters[pp.Namespace] = make(map[string]*selectorCounter)
+	}
+	c.counters[pp.Namespace][key] = counter
+	return counter, nil
+}
+
+// pruneIdleCounters drops the counters no placement policy looked up for a while, at most once
+// every idle timeout. It must be called with the lock held.
+func (c *PlacementCounters) pruneIdleCounters() {
+	now := c.now()
+	if now.Sub(c.lastPruned) < counterIdleTimeout {
+		return
+	}
+	c.lastPruned = now
+	for namespace, counters := range c.counters {
+		for key, counter := range counters {
+			if now.Sub(counter.lastUsed) >= counterIdleTimeout {
+				delete(counters, key)
+			}
+		}
+		if len(counters) == 0 {
+			delete(c.counters, namespace)
+		}
+	}
+}
+
+func (c *PlacementCounters) updatePod(pod *podRecord) {
+	c.Lock()
+	defer c.Unlock()
+	if old, ok := c.pods[pod.namespace][pod.uid]; ok {
+		// the other updates of the pod, such as its status, don't change the counters
+		if old.nodeName == pod.nodeName && labels.Equals(old.labels, pod.labels) {
+			return
+		}
+		c.removePod(old)
+	}
+	c.addPod(pod)
+}
+
+func (c *PlacementCounters) deletePod(namespace string, uid types.UID) {
+	c.Lock()
+	defer c.Unlock()
+	if pod, ok := c.pods[namespace][uid]; ok {
+		c.removePod(pod)
+	}
+}
+
+// updateNode recounts the pods bound to the node with the new labels of the node, or as pods on
+// an unknown node if the node is deleted
+func (c *PlacementCounters) updateNode(nodeName string, nodeLabels labels.Set, exists bool) {
+	c.Lock()
+	defer c.Unlock()
+	oldLabels, existed := c.nodeLabels[nodeName]
+	if existed == exists && labels.Equals(oldLabels, nodeLabels) {
+		return
+	}
+	pods := c.podsOnNode[nodeName]
+	for _, pod := range pods {
+		c.countInNamespace(pod, -1)
+	}
+	if exists {
+		c.nodeLabels[nodeName] = nodeLabels
+	} else {
+		delete(c.nodeLabels, nodeName)
+	}
+	for _, pod := range pods {
+		c.countInNamespace(pod, 1)
+	}
+}
+
+func (c *PlacementCounters) addPod(pod *podRecord) {
+	if _, ok := c.pods[pod.namespace]; !ok {
+		c.pods[pod.namespace] = make(map[types.UID]*podRecord)
+	}
+	c.pods[pod.namespace][pod.uid] = pod
+	if pod.nodeName != "" {
+		if _, ok := c.podsOnNode[pod.nodeName]; !ok {
+			c.podsOnNode[pod.nodeName] = make(map[types.UID]*podRecord)
+		}
+		c.podsOnNode[pod.nodeName][pod.uid] = pod
+	}
+	c.countInNamespace(pod, 1)
+}
+
+func (c *PlacementCounters) removePod(pod *podRecord) {
+	c.countInNamespace(pod, -1)
+	delete(c.pods[pod.namespace], pod.uid)
+	if len(c.pods[pod.namespace]) == 0 {
+		delete(c.pods, pod.namespace)
+	}
+	if pod.nodeName != "" {
+		delete(c.podsOnNode[pod.nodeName], pod.uid)
+		if len(c.podsOnNode[pod.nodeName]) == 0 {
+			delete(c.podsOnNode, pod.nodeName)
+		}
+	}
+}
+
+// countInNamespace adds delta times the pod to the counters of its namespace
+func (c *PlacementCounters) countInNamespace(pod *podRecord, delta int) {
+	for _, counter := range c.counters[pod.namespace] {
+		c.count(counter, pod, delta)
+	}
+}
+
+// count adds delta times the pod to the counter, if it matches its pod selector. As when the pods
+// are grouped from the full list, the pods bound to a node that is not known are counted on
+// the nodes without matching labels.
+func (c *PlacementCounters) count(counter *selectorCounter, pod *podRecord, delta int) {
+	if !counter.podSelector.Matches(pod.labels) {
+		return
+	}
+	counter.totalPods += delta
+	if pod.nodeName == "" {
+		if delta > 0 {
+			counter.pendingPods[pod.uid] = true
+		} else {
+			delete(counter.pendingPods, pod.uid)
+		}
+		return
+	}
+	if nodeLabels, ok := c.nodeLabels[pod.nodeName]; ok && counter.nodeSelector.Matches(nodeLabels) {
+		counter.podsOnNodeWithMatchingLabels += delta
+	}
+}
+
+func newPodRecord(pod *corev1.Pod) *podRecord {
+	return &podRecord{
+		uid:       pod.UID,
+		namespace: pod.Namespace,
+		labels:    labels.Set(pod.Labels),
+		nodeName:  pod.Spec.NodeName,
+	}
+}
+
+// counterKey returns the key of the counter of the placement policy in its namespace, from its
+// pod and node selectors, and the compiled selectors
+func counterKey(pp *v1alpha1.PlacementPolicy) (string, labels.Selector, labels.Selector, error) {
+	podSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.PodSelector)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to parse pod selector: %w", err)
+	}
+	nodeSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.NodeSelector)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to parse node selector: %w", err)
+	}
+	// a null selector matches nothing, while an empty one matches everything, but both are
+	// formatted as an empty string
+	selectorKey := func(selector *metav1.LabelSelector, compiled labels.Selector) string {
+		if selector == nil {
+			return "null"
+		}
+		return "{" + compiled.String() + "}"
+	}
+	return selectorKey(pp.Spec.PodSelector, podSelector) + " " + selectorKey(pp.Spec.NodeSelector, nodeSelector), podSelector, nodeSelector, nil
+}
@@ -0,0 +1,238 @@
+package core
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// recount counts the pods of the placement policy from the full lists of pods and nodes
+func recount(t *testing.T, pp *v1alpha1.PlacementPolicy, pods map[types.UID]*corev1.Pod, nodes map[string]*corev1.Node) PlacementCount {
+	podSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.PodSelector)
+	if err != nil {
+		t.Fatalf("failed to parse pod selector: %v", err)
+	}
+	nodeSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.NodeSelector)
+	if err != nil {
+		t.Fatalf("failed to parse node selector: %v", err)
+	}
+	count := PlacementCount{}
+	for _, pod := range pods {
+		if pod.Namespace != pp.Namespace || !podSelector.Matches(labels.Set(pod.Labels)) || !IsCountedPod(pod) {
+			continue
+		}
+		count.TotalPods++
+		if pod.Spec.NodeName == "" {
+			count.PendingPods = append(count.PendingPods, pod.UID)
+			continue
+		}
+		count.PodsOnNodes++
+		if node, ok := nodes[pod.Spec.NodeName]; ok && nodeSelector.Matches(labels.Set(node.Labels)) {
+			count.PodsOnNodeWithMatchingLabels++
+		}
+	}
+	return count
+}
+
+func TestPlacementCounters(t *testing.T) {
+	newPP := func(name string, podSelector, nodeSelector *metav1.LabelSelector) *v1alpha1.PlacementPolicy {
+		return &v1alpha1.PlacementPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1alpha1.PlacementPolicySpec{
+				PodSelector:  podSelector,
+				NodeSelector: nodeSelector,
+				Policy:       &v1alpha1.Policy{},
+			},
+		}
+	}
+	policies := []*v1alpha1.PlacementPolicy{
+		newPP("nginx", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}, &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}}),
+		newPP("all-pods", &metav1.LabelSelector{}, &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "node", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"want"}},
+		}}),
+		newPP("no-pods", nil, &metav1.LabelSelector{}),
+	}
+
+	c := NewPlacementCounters()
+	podHandler := c.PodEventHandler()
+	nodeHandler := c.NodeEventHandler()
+	pods := make(map[types.UID]*corev1.Pod)
+	nodes := make(map[string]*corev1.Node)
+
+	check := func(step string) {
+		t.Helper()
+		for _, pp := range policies {
+			got, _, err := c.GetCount(pp)
+			if err != nil {
+				t.Fatalf("%s: GetCount() error = %v", step, err)
+			}
+			want := recount(t, pp, pods, nodes)
+			sort.Slice(got.PendingPods, func(i, j int) bool { return got.PendingPods[i] < got.PendingPods[j] })
+			sort.Slice(want.PendingPods, func(i, j int) bool { return want.PendingPods[i] < want.PendingPods[j] })
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Fatalf("%s: GetCount(%s) = %+v, want %+v", step, pp.Name, got, want)
+			}
+		}
+	}
+
+	// the pods known before the counters of a placement policy are looked up are counted as well
+	for i := 0; i < 3; i++ {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node%d", i), Labels: map[string]string{"node": "want"}}}
+		nodes[node.Name] = node
+		nodeHandler.OnAdd(node)
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", UID: "pod", Labels: map[string]string{"app": "nginx"}}, Spec: corev1.PodSpec{NodeName: "node0"}}
+	pods[pod.UID] = pod
+	podHandler.OnAdd(pod)
+	check("counters created")
+
+	// random events, the counters being checked against a full recount after each of them
+	r := rand.New(rand.NewSource(1))
+	apps := []string{"nginx", "redis"}
+	nodeLabels := []string{"want", "unwant"}
+	for i := 0; i < 500; i++ {
+		var step string
+		switch event := r.Intn(6); {
+		case event == 0:
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("pod-%d", i),
+				Namespace: []string{"default", "other"}[r.Intn(2)],
+				UID:       types.UID(fmt.Sprintf("pod-%d", i)),
+				Labels:    map[string]string{"app": apps[r.Intn(len(apps))]},
+			}}
+			pods[pod.UID] = pod
+			podHandler.OnAdd(pod)
+			step = fmt.Sprintf("pod %s added", pod.Name)
+		case event <= 3 && len(pods) > 0:
+			old := randomPod(r, pods)
+			pod := old.DeepCopy()
+			if event == 1 {
+				pod.Labels["app"] = apps[r.Intn(len(apps))]
+			} else if event == 2 {
+				pod.Spec.NodeName = fmt.Sprintf("node%d", r.Intn(len(nodes)+1))
+			} else {
+				pod.Status.Phase = []corev1.PodPhase{corev1.PodRunning, corev1.PodSucceeded}[r.Intn(2)]
+			}
+			pods[pod.UID] = pod
+			podHandler.OnUpdate(old, pod)
+			step = fmt.Sprintf("pod %s updated", pod.Name)
+		case event == 4 && len(pods) > 0:
+			pod := randomPod(r, pods)
+			delete(pods, pod.UID)
+			if r.Intn(2) == 0 {
+				podHandler.OnDelete(pod)
+			} else {
+				podHandler.OnDelete(cache.DeletedFinalStateUnknown{Key: pod.Name, Obj: pod})
+			}
+			step = fmt.Sprintf("pod %s deleted", pod.Name)
+		default:
+			name := fmt.Sprintf("node%d", r.Intn(4))
+			old, ok := nodes[name]
+			if ok && r.Intn(3) == 0 {
+				delete(nodes, name)
+				nodeHandler.OnDelete(old)
+				step = fmt.Sprintf("node %s deleted", name)
+				break
+			}
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"node": nodeLabels[r.Intn(len(nodeLabels))]}}}
+			nodes[name] = node
+			if ok {
+				nodeHandler.OnUpdate(old, node)
+			} else {
+				nodeHandler.OnAdd(node)
+			}
+			step = fmt.Sprintf("node %s updated", name)
+		}
+		check(step)
+	}
+}
+
+func TestPlacementCountersCheckAndPrune(t *testing.T) {
+	pp := &v1alpha1.PlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "default"},
+		Spec: v1alpha1.PlacementPolicySpec{
+			PodSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+			Policy:       &v1alpha1.Policy{},
+		},
+	}
+	now := time.Now()
+	c := NewPlacementCounters()
+	c.now = func() time.Time { return now }
+
+	if _, check, _ := c.GetCount(pp); check {
+		t.Errorf("GetCount() check = true for new counters, want false")
+	}
+	now = now.Add(counterCheckPeriod)
+	if _, check, _ := c.GetCount(pp); !check {
+		t.Errorf("GetCount() check = false after %v, want true", counterCheckPeriod)
+	}
+	if _, check, _ := c.GetCount(pp); check {
+		t.Errorf("GetCount() check = true right after a check, want false")
+	}
+
+	now = now.Add(counterIdleTimeout)
+	c.Lock()
+	c.pruneIdleCounters()
+	c.Unlock()
+	if len(c.counters) != 0 {
+		t.Errorf("counters = %v after %v, want idle counters dropped", c.counters, counterIdleTimeout)
+	}
+}
+
+func TestCountersSupported(t *testing.T) {
+	tests := []struct {
+		name string
+		spec v1alpha1.PlacementPolicySpec
+		want bool
+	}{
+		{
+			name: "pods of the namespace",
+			spec: v1alpha1.PlacementPolicySpec{Policy: &v1alpha1.Policy{}},
+			want: true,
+		},
+		{
+			name: "namespace selector",
+			spec: v1alpha1.PlacementPolicySpec{NamespaceSelector: &metav1.LabelSelector{}, Policy: &v1alpha1.Policy{}},
+		},
+		{
+			name: "per owner",
+			spec: v1alpha1.PlacementPolicySpec{Policy: &v1alpha1.Policy{Scope: v1alpha1.ScopePerOwner}},
+		},
+		{
+			name: "node groups",
+			spec: v1alpha1.PlacementPolicySpec{Policy: &v1alpha1.Policy{Groups: []v1alpha1.NodeGroup{{Name: "spot"}}}},
+		},
+		{
+			name: "topology spread",
+			spec: v1alpha1.PlacementPolicySpec{Policy: &v1alpha1.Policy{TopologyKey: "topology.kubernetes.io/zone"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountersSupported(&v1alpha1.PlacementPolicy{Spec: tt.spec}); got != tt.want {
+				t.Errorf("CountersSupported() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// randomPod returns a random pod, in a deterministic order for the same random source
+func randomPod(r *rand.Rand, pods map[types.UID]*corev1.Pod) *corev1.Pod {
+	uids := make([]string, 0, len(pods))
+	for uid := range pods {
+		uids = append(uids, string(uid))
+	}
+	sort.Strings(uids)
+	return pods[types.UID(uids[r.Intn(len(uids))])]
+}
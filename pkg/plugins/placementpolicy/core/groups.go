@@ -0,0 +1,74 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// GetNodeGroupSelectors returns the node selectors of the node groups of the placement
+// policy, in the order of the groups
+func GetNodeGroupSelectors(pp *v1alpha1.PlacementPolicy) ([]labels.Selector, error) {
+	selectors := make([]labels.Selector, 0, len(pp.Spec.Policy.Groups))
+	for _, group := range pp.Spec.Policy.Groups {
+		selector, err := metav1.LabelSelectorAsSelector(group.NodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse node selector of node group %s: %w", group.Name, err)
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
+
+// GetNodeGroup returns the index of the first node group whose node selector matches the
+// labels of the node, or -1 if the node doesn't belong to any node group
+func GetNodeGroup(node *corev1.Node, selectors []labels.Selector) int {
+	for i, selector := range selectors {
+		if selector.Matches(labels.Set(node.Labels)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetNodeGroupTargets returns the number of pods to place on the nodes of each node group out of
+// totalPods, followed by the number of pods to place on the nodes outside of the groups, in
+// hundredths of a pod. The targets are not rounded, so the pods are distributed in proportion to
+// the target sizes, and the nodes outside of the groups target the pods beyond the targets of all
+// the groups. The groups first in the list get their target when the target sizes exceed the total.
+func GetNodeGroupTargets(groups []v1alpha1.NodeGroup, totalPods int) ([]int, error) {
+	targets := make([]int, 0, len(groups)+1)
+	remaining := 100 * totalPods
+	for _, group := range groups {
+		target, err := getScaledNodeGroupTarget(group.TargetSize, totalPods)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target size of node group %s: %w", group.Name, err)
+		}
+		if target > remaining {
+			target = remaining
+		}
+		remaining -= target
+		targets = append(targets, target)
+	}
+	return append(targets, remaining), nil
+}
+
+// getScaledNodeGroupTarget returns the target of a node group out of totalPods, in hundredths of a pod
+func getScaledNodeGroupTarget(targetSize *intstr.IntOrString, totalPods int) (int, error) {
+	if targetSize == nil {
+		return 0, fmt.Errorf("target size is not set")
+	}
+	if targetSize.Type == intstr.String {
+		percent, err := intstr.GetScaledValueFromIntOrPercent(targetSize, 100, false)
+		if err != nil {
+			return 0, err
+		}
+		return percent * totalPods, nil
+	}
+	return 100 * targetSize.IntValue(), nil
+}
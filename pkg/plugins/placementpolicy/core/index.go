@@ -0,0 +1,288 @@
+package core
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	pplisters "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/listers/apis/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// maxCachedMatchesPerNamespace bounds the number of label sets whose matching placement policies
+// are cached in a namespace, as pods with unique labels would otherwise grow the cache forever
+const maxCachedMatchesPerNamespace = 10000
+
+// compiledPolicy is a placement policy with its selectors parsed once
+type compiledPolicy struct {
+	// pp is the placement policy with the defaults applied, nil for cluster placement policies
+	pp *v1alpha1.PlacementPolicy
+	// cpp is the cluster placement policy, nil for placement policies
+	cpp *v1alpha1.ClusterPlacementPolicy
+	// podSelector is the compiled pod selector of the policy
+	podSelector labels.Selector
+	// namespaceSelector is the compiled namespace selector of the cluster placement policy,
+	// nil if it matches all namespaces
+	namespaceSelector labels.Selector
+}
+
+// policyMatch are the placement policies matching the pods with the same labels in a namespace
+type policyMatch struct {
+	// policies are the matching placement policies, in the order of precedence
+	policies []*v1alpha1.PlacementPolicy
+	// clusterPolicies is true if the cluster placement policies were looked up, in which
+	// case the match depends on the labels of the namespace as well
+	clusterPolicies bool
+	// namespaceLabels are the labels of the namespace the cluster placement policies were matched against
+	namespaceLabels labels.Set
+}
+
+// policyIndex is an in-memory index of the placement policies with their compiled selectors,
+// which also caches the placement policies matching the pods. The matches are keyed by the
+// namespace and the labels of the pods, so the replicas of a workload share them and a pod
+// whose labels change is matched again. The index is filled from the listers on first use,
+// and invalidated by the events of the placement policy informers.
+type policyIndex struct {
+	sync.RWMutex
+	ppLister        pplisters.PlacementPolicyLister
+	cppLister       pplisters.ClusterPlacementPolicyLister
+	namespaceLister corelisters.NamespaceLister
+	// withDefaults returns a copy of the placement policy with the defaults applied
+	withDefaults func(*v1alpha1.PlacementPolicy) *v1alpha1.PlacementPolicy
+
+	// namespacePolicies are the compiled placement policies of each namespace
+	namespacePolicies map[string][]*compiledPolicy
+	// clusterPolicies are the compiled cluster placement policies, nil until they are listed
+	clusterPolicies []*compiledPolicy
+	// matches are the placement policies matching the pods of each namespace, keyed by the labels of the pods
+	matches map[string]map[string]*policyMatch
+}
+
+func newPolicyIndex(ppLister pplisters.PlacementPolicyLister, cppLister pplisters.ClusterPlacementPolicyLister, namespaceLister corelisters.NamespaceLister, withDefaults func(*v1alpha1.PlacementPolicy) *v1alpha1.PlacementPolicy) *policyIndex {
+	return &policyIndex{
+		ppLister:          ppLister,
+		cppLister:         cppLister,
+		namespaceLister:   namespaceLister,
+		withDefaults:      withDefaults,
+		namespacePolicies: make(map[string][]*compiledPolicy),
+		matches:           make(map[string]map[string]*policyMatch),
+	}
+}
+
+// placementPolicyHandler returns the event handler invalidating the placement policies of the
+// namespace of a placement policy that is added, deleted or whose spec changes
+func (idx *policyIndex) placementPolicyHandler() cache.ResourceEventHandler {
+	invalidate := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		pp, ok := obj.(*v1alpha1.PlacementPolicy)
+		if !ok {
+			return
+		}
+		idx.Lock()
+		defer idx.Unlock()
+		delete(idx.namespacePolicies, pp.Namespace)
+		delete(idx.matches, pp.Namespace)
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: invalidate,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPP, oldOK := oldObj.(*v1alpha1.PlacementPolicy)
+			newPP, newOK := newObj.(*v1alpha1.PlacementPolicy)
+			// the status updates don't change the placement policies matching the pods
+			if oldOK && newOK && equality.Semantic.DeepEqual(oldPP.Spec, newPP.Spec) {
+				return
+			}
+			invalidate(newObj)
+		},
+		DeleteFunc: invalidate,
+	}
+}
+
+// clusterPlacementPolicyHandler returns the event handler invalidating the cluster placement
+// policies, and the matches of all the namespaces, when a cluster placement policy is added,
+// deleted or its spec changes
+func (idx *policyIndex) clusterPlacementPolicyHandler() cache.ResourceEventHandler {
+	invalidate := func(interface{}) {
+		idx.Lock()
+		defer idx.Unlock()
+		idx.clusterPolicies = nil
+		idx.matches = make(map[string]map[string]*policyMatch)
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: invalidate,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldCPP, oldOK := oldObj.(*v1alpha1.ClusterPlacementPolicy)
+			newCPP, newOK := newObj.(*v1alpha1.ClusterPlacementPolicy)
+			if oldOK && newOK && equality.Semantic.DeepEqual(oldCPP.Spec, newCPP.Spec) {
+				return
+			}
+			invalidate(newObj)
+		},
+		DeleteFunc: invalidate,
+	}
+}
+
+// getPlacementPoliciesForPod returns the placement policies matching the pod, in the order of
+// precedence. The placement policies in the namespace of the pod take precedence over the
+// cluster placement policies. The returned policies are shared and must not be modified.
+func (idx *policyIndex) getPlacementPoliciesForPod(pod *corev1.Pod) ([]*v1alpha1.PlacementPolicy, error) {
+	key := labels.Set(pod.Labels).String()
+	idx.RLock()
+	match := idx.matches[pod.Namespace][key]
+	idx.RUnlock()
+	if match != nil {
+		if !match.clusterPolicies {
+			return match.policies, nil
+		}
+		namespace, err := idx.namespaceLister.Get(pod.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if labels.Equals(match.namespaceLabels, namespace.Labels) {
+			return match.policies, nil
+		}
+	}
+
+	// the index is filled under the lock, so it can't miss the events of the informers
+	// that happen while the listers are read
+	idx.Lock()
+	defer idx.Unlock()
+	match, err := idx.matchPod(pod)
+	if err != nil {
+		return nil, err
+	}
+	namespaceMatches, ok := idx.matches[pod.Namespace]
+	if !ok || len(namespaceMatches) >= maxCachedMatchesPerNamespace {
+		namespaceMatches = make(map[string]*policyMatch)
+		idx.matches[pod.Namespace] = namespaceMatches
+	}
+	namespaceMatches[key] = match
+	return match.policies, nil
+}
+
+// matchPod matches the pod against the placement policies of its namespace or, if none
+// matches, against the cluster placement policies. It must be called with the lock held.
+func (idx *policyIndex) matchPod(pod *corev1.Pod) (*policyMatch, error) {
+	podLabels := labels.Set(pod.Labels)
+	policies, ok := idx.namespacePolicies[pod.Namespace]
+	if !ok {
+		ppList, err := idx.ppLister.PlacementPolicies(pod.Namespace).List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		policies = make([]*compiledPolicy, 0, len(ppList))
+		for _, pp := range ppList {
+			if policy := idx.compilePlacementPolicy(pp); policy != nil {
+				policies = append(policies, policy)
+			}
+		}
+		idx.namespacePolicies[pod.Namespace] = policies
+	}
+
+	match := &policyMatch{}
+	for _, policy := range policies {
+		if policy.podSelector.Matches(podLabels) {
+			match.policies = append(match.policies, policy.pp)
+		}
+	}
+	if len(match.policies) == 0 {
+		// no placement policy in the namespace of the pod, fall back to the cluster placement policies
+		if err := idx.matchClusterPolicies(pod, match); err != nil {
+			return nil, err
+		}
+	}
+	if len(match.policies) > 1 {
+		// if there are multiple placement policies, sort them in the order of precedence
+		sort.Sort(ByWeight(match.policies))
+	}
+	return match, nil
+}
+
+// matchClusterPolicies adds the cluster placement policies matching the namespace and the labels
+// of the pod to the match, as placement policies in the namespace of the pod
+func (idx *policyIndex) matchClusterPolicies(pod *corev1.Pod, match *policyMatch) error {
+	if idx.clusterPolicies == nil {
+		cppList, err := idx.cppLister.List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		idx.clusterPolicies = make([]*compiledPolicy, 0, len(cppList))
+		for _, cpp := range cppList {
+			if policy := compileClusterPlacementPolicy(cpp); policy != nil {
+				idx.clusterPolicies = append(idx.clusterPolicies, policy)
+			}
+		}
+	}
+	if len(idx.clusterPolicies) == 0 {
+		return nil
+	}
+
+	namespace, err := idx.namespaceLister.Get(pod.Namespace)
+	if err != nil {
+		return err
+	}
+	match.clusterPolicies = true
+	match.namespaceLabels = labels.Set(namespace.Labels)
+	podLabels := labels.Set(pod.Labels)
+	for _, policy := range idx.clusterPolicies {
+		// a null namespace selector matches all namespaces, unlike in label selector conversion
+		if policy.namespaceSelector != nil && !policy.namespaceSelector.Matches(match.namespaceLabels) {
+			continue
+		}
+		if policy.podSelector.Matches(podLabels) {
+			match.policies = append(match.policies, idx.withDefaults(policy.cpp.PlacementPolicyForNamespace(pod.Namespace)))
+		}
+	}
+	return nil
+}
+
+// compilePlacementPolicy returns the placement policy with the defaults applied and its pod
+// selector compiled, or nil if the placement policy can't be applied
+func (idx *policyIndex) compilePlacementPolicy(pp *v1alpha1.PlacementPolicy) *compiledPolicy {
+	// placement policies created before the validating webhook was installed
+	// may be missing the policy, and can't be applied
+	if pp.Spec.Policy == nil {
+		klog.InfoS("skipping placement policy without policy", "placementPolicy", klog.KObj(pp))
+		return nil
+	}
+	podSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.PodSelector)
+	if err != nil {
+		klog.ErrorS(err, "invalid pod selector in placement policy", "placementPolicy", klog.KObj(pp))
+		return nil
+	}
+	return &compiledPolicy{pp: idx.withDefaults(pp), podSelector: podSelector}
+}
+
+// compileClusterPlacementPolicy returns the cluster placement policy with its selectors
+// compiled, or nil if the cluster placement policy can't be applied
+func compileClusterPlacementPolicy(cpp *v1alpha1.ClusterPlacementPolicy) *compiledPolicy {
+	if cpp.Spec.Policy == nil {
+		klog.InfoS("skipping cluster placement policy without policy", "clusterPlacementPolicy", klog.KObj(cpp))
+		return nil
+	}
+	policy := &compiledPolicy{cpp: cpp}
+	if cpp.Spec.NamespaceSelector != nil {
+		namespaceSelector, err := metav1.LabelSelectorAsSelector(cpp.Spec.NamespaceSelector)
+		if err != nil {
+			klog.ErrorS(err, "invalid namespace selector in cluster placement policy", "clusterPlacementPolicy", klog.KObj(cpp))
+			return nil
+		}
+		policy.namespaceSelector = namespaceSelector
+	}
+	podSelector, err := metav1.LabelSelectorAsSelector(cpp.Spec.PodSelector)
+	if err != nil {
+		klog.ErrorS(err, "invalid pod selector in cluster placement policy", "clusterPlacementPolicy", klog.KObj(cpp))
+		return nil
+	}
+	policy.podSelector = podSelector
+	return policy
+}
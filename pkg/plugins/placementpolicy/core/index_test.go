@@ -0,0 +1,200 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	pplisters "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/listers/apis/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// testPolicyIndex is a policy index whose informer caches are updated by the tests
+type testPolicyIndex struct {
+	*policyIndex
+	namespaceIndexer cache.Indexer
+	ppIndexer        cache.Indexer
+	cppIndexer       cache.Indexer
+}
+
+func newTestPolicyIndex() *testPolicyIndex {
+	namespaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	ppIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	cppIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	m := &PlacementPolicyManager{}
+	return &testPolicyIndex{
+		policyIndex: newPolicyIndex(pplisters.NewPlacementPolicyLister(ppIndexer), pplisters.NewClusterPlacementPolicyLister(cppIndexer),
+			corelisters.NewNamespaceLister(namespaceIndexer), m.withDefaults),
+		namespaceIndexer: namespaceIndexer,
+		ppIndexer:        ppIndexer,
+		cppIndexer:       cppIndexer,
+	}
+}
+
+func newIndexTestPlacementPolicy(namespace, name, app string) *v1alpha1.PlacementPolicy {
+	return &v1alpha1.PlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: v1alpha1.PlacementPolicySpec{
+			PodSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+			Policy:       &v1alpha1.Policy{},
+		},
+	}
+}
+
+func TestPolicyIndex(t *testing.T) {
+	idx := newTestPolicyIndex()
+	ppHandler := idx.placementPolicyHandler()
+	cppHandler := idx.clusterPlacementPolicyHandler()
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"team": "a"}}}
+	if err := idx.namespaceIndexer.Add(namespace); err != nil {
+		t.Fatalf("failed to add namespace: %v", err)
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", Labels: map[string]string{"app": "nginx"}}}
+
+	wantPolicies := func(step string, want ...string) {
+		t.Helper()
+		ppList, err := idx.getPlacementPoliciesForPod(pod)
+		if err != nil {
+			t.Fatalf("%s: getPlacementPoliciesForPod() error = %v", step, err)
+		}
+		var got []string
+		for _, pp := range ppList {
+			got = append(got, pp.Name)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: getPlacementPoliciesForPod() = %v, want %v", step, got, want)
+		}
+	}
+
+	wantPolicies("no placement policy")
+
+	pp := newIndexTestPlacementPolicy("default", "pp", "nginx")
+	if err := idx.ppIndexer.Add(pp); err != nil {
+		t.Fatalf("failed to add placement policy: %v", err)
+	}
+	ppHandler.OnAdd(pp)
+	wantPolicies("placement policy added", "pp")
+
+	// a status update doesn't invalidate the index
+	other := newIndexTestPlacementPolicy("default", "other", "nginx")
+	if err := idx.ppIndexer.Add(other); err != nil {
+		t.Fatalf("failed to add placement policy: %v", err)
+	}
+	updated := pp.DeepCopy()
+	updated.Status.ObservedGeneration = 1
+	ppHandler.OnUpdate(pp, updated)
+	wantPolicies("placement policy status updated", "pp")
+	ppHandler.OnAdd(other)
+	wantPolicies("other placement policy added", "other", "pp")
+
+	// the placement policies of other namespaces don't invalidate the matches of the namespace
+	ppHandler.OnAdd(newIndexTestPlacementPolicy("kube-system", "pp", "nginx"))
+	if _, ok := idx.matches["default"]; !ok {
+		t.Errorf("placement policy added in another namespace invalidated the matches of the namespace")
+	}
+
+	pod.Labels["app"] = "redis"
+	wantPolicies("pod labels changed")
+
+	cpp := &v1alpha1.ClusterPlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cpp"},
+		Spec: v1alpha1.ClusterPlacementPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+			PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "redis"}},
+			NodeSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+			Policy:            &v1alpha1.Policy{},
+		},
+	}
+	if err := idx.cppIndexer.Add(cpp); err != nil {
+		t.Fatalf("failed to add cluster placement policy: %v", err)
+	}
+	cppHandler.OnAdd(cpp)
+	wantPolicies("cluster placement policy added", "cpp")
+
+	relabeled := namespace.DeepCopy()
+	relabeled.Labels["team"] = "b"
+	if err := idx.namespaceIndexer.Update(relabeled); err != nil {
+		t.Fatalf("failed to update namespace: %v", err)
+	}
+	wantPolicies("namespace labels changed")
+
+	if err := idx.cppIndexer.Delete(cpp); err != nil {
+		t.Fatalf("failed to delete cluster placement policy: %v", err)
+	}
+	cppHandler.OnDelete(cache.DeletedFinalStateUnknown{Key: cpp.Name, Obj: cpp})
+	if idx.clusterPolicies != nil {
+		t.Errorf("cluster placement policy deleted didn't invalidate the cluster placement policies")
+	}
+}
+
+// BenchmarkGetPlacementPolicyForPod looks up the placement policy of the pods in a cluster with
+// thousands of placement policies, most of them not matching the pods
+func BenchmarkGetPlacementPolicyForPod(b *testing.B) {
+	const namespaces, policiesPerNamespace, clusterPolicies = 100, 50, 20
+	idx := newTestPolicyIndex()
+	for i := 0; i < namespaces; i++ {
+		namespace := fmt.Sprintf("ns-%d", i)
+		if err := idx.namespaceIndexer.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}); err != nil {
+			b.Fatalf("failed to add namespace: %v", err)
+		}
+		for j := 0; j < policiesPerNamespace; j++ {
+			pp := newIndexTestPlacementPolicy(namespace, fmt.Sprintf("pp-%d", j), fmt.Sprintf("app-%d", j))
+			if err := idx.ppIndexer.Add(pp); err != nil {
+				b.Fatalf("failed to add placement policy: %v", err)
+			}
+		}
+	}
+	for i := 0; i < clusterPolicies; i++ {
+		cpp := &v1alpha1.ClusterPlacementPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cpp-%d", i)},
+			Spec: v1alpha1.ClusterPlacementPolicySpec{
+				PodSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": fmt.Sprintf("cluster-app-%d", i)}},
+				NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+				Policy:       &v1alpha1.Policy{},
+			},
+		}
+		if err := idx.cppIndexer.Add(cpp); err != nil {
+			b.Fatalf("failed to add cluster placement policy: %v", err)
+		}
+	}
+	m := &PlacementPolicyManager{index: idx.policyIndex}
+	ctx := context.Background()
+	newPod := func(i int, app string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("pod-%d", i),
+			Namespace: fmt.Sprintf("ns-%d", i%namespaces),
+			Labels:    map[string]string{"app": app, "pod-template-hash": "5d59d67564"},
+		}}
+	}
+
+	b.Run("placement policy", func(b *testing.B) {
+		pods := make([]*corev1.Pod, 0, 1000)
+		for i := 0; i < cap(pods); i++ {
+			pods = append(pods, newPod(i, fmt.Sprintf("app-%d", i%policiesPerNamespace)))
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := m.GetPlacementPolicyForPod(ctx, pods[i%len(pods)]); err != nil {
+				b.Fatalf("GetPlacementPolicyForPod() error = %v", err)
+			}
+		}
+	})
+	b.Run("cluster placement policy", func(b *testing.B) {
+		pods := make([]*corev1.Pod, 0, 1000)
+		for i := 0; i < cap(pods); i++ {
+			pods = append(pods, newPod(i, fmt.Sprintf("cluster-app-%d", i%clusterPolicies)))
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := m.GetPlacementPolicyForPod(ctx, pods[i%len(pods)]); err != nil {
+				b.Fatalf("GetPlacementPolicyForPod() error = %v", err)
+			}
+		}
+	})
+}
@@ -2,6 +2,8 @@ package core
 
 import "github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
 
+// ByWeight sorts placement policies in the order of precedence: the highest
+// weight first, then Strict before BestEffort, then alphabetically by name.
 type ByWeight []*v1alpha1.PlacementPolicy
 
 func (a ByWeight) Len() int { return len(a) }
@@ -11,5 +13,22 @@ func (a ByWeight) Swap(i, j int) {
 }
 
 func (a ByWeight) Less(i, j int) bool {
-	return a[i].Spec.Weight > a[j].Spec.Weight
+	return HasPrecedence(a[i], a[j])
+}
+
+// HasPrecedence returns true if the placement policy a takes precedence over b
+// when both match a pod.
+func HasPrecedence(a, b *v1alpha1.PlacementPolicy) bool {
+	if a.Spec.Weight != b.Spec.Weight {
+		return a.Spec.Weight > b.Spec.Weight
+	}
+	aStrict := a.Spec.EnforcementMode == v1alpha1.EnforcementModeStrict
+	bStrict := b.Spec.EnforcementMode == v1alpha1.EnforcementModeStrict
+	if aStrict != bStrict {
+		return aStrict
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.Namespace < b.Namespace
 }
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestPolicy(name string, weight int32, mode v1alpha1.EnforcementMode) *v1alpha1.PlacementPolicy {
+	return &v1alpha1.PlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       v1alpha1.PlacementPolicySpec{Weight: weight, EnforcementMode: mode},
+	}
+}
+
+func TestHasPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *v1alpha1.PlacementPolicy
+		b    *v1alpha1.PlacementPolicy
+		want bool
+	}{
+		{
+			name: "higher weight",
+			a:    newTestPolicy("b", 200, v1alpha1.EnforcementModeBestEffort),
+			b:    newTestPolicy("a", 101, v1alpha1.EnforcementModeStrict),
+			want: true,
+		},
+		{
+			name: "lower weight",
+			a:    newTestPolicy("a", 101, v1alpha1.EnforcementModeStrict),
+			b:    newTestPolicy("b", 200, v1alpha1.EnforcementModeBestEffort),
+			want: false,
+		},
+		{
+			name: "strict over best effort with the same weight",
+			a:    newTestPolicy("b", 101, v1alpha1.EnforcementModeStrict),
+			b:    newTestPolicy("a", 101, v1alpha1.EnforcementModeBestEffort),
+			want: true,
+		},
+		{
+			name: "best effort under strict with the same weight",
+			a:    newTestPolicy("a", 101, v1alpha1.EnforcementModeBestEffort),
+			b:    newTestPolicy("b", 101, v1alpha1.EnforcementModeStrict),
+			want: false,
+		},
+		{
+			name: "alphabetical order with the same weight and enforcement mode",
+			a:    newTestPolicy("a", 101, v1alpha1.EnforcementModeStrict),
+			b:    newTestPolicy("b", 101, v1alpha1.EnforcementModeStrict),
+			want: true,
+		},
+		{
+			name: "same policy",
+			a:    newTestPolicy("a", 101, v1alpha1.EnforcementModeStrict),
+			b:    newTestPolicy("a", 101, v1alpha1.EnforcementModeStrict),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasPrecedence(tt.a, tt.b); got != tt.want {
+				t.Errorf("HasPrecedence() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortByWeight(t *testing.T) {
+	want := []string{"d", "b", "c", "a", "e"}
+	ppList := []*v1alpha1.PlacementPolicy{
+		newTestPolicy("a", 101, v1alpha1.EnforcementModeBestEffort),
+		newTestPolicy("b", 101, v1alpha1.EnforcementModeStrict),
+		newTestPolicy("c", 101, v1alpha1.EnforcementModeStrict),
+		newTestPolicy("d", 300, v1alpha1.EnforcementModeBestEffort),
+		newTestPolicy("e", 101, v1alpha1.EnforcementModeBestEffort),
+	}
+
+	// the order must not depend on the order the policies are listed in
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		r.Shuffle(len(ppList), func(i, j int) { ppList[i], ppList[j] = ppList[j], ppList[i] })
+		sort.Sort(ByWeight(ppList))
+
+		got := make([]string, 0, len(ppList))
+		for _, pp := range ppList {
+			got = append(got, pp.Name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("sort.Sort(ByWeight) = %v, want %v", got, want)
+		}
+	}
+}
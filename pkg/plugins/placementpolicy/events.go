@@ -0,0 +1,61 @@
+package placementpolicy
+
+import (
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// ReasonPlacementPolicyApplied is the event reason when a placement policy is applied to a pod
+	ReasonPlacementPolicyApplied = "PlacementPolicyApplied"
+	// ReasonPlacementPolicyConflict is the event reason when multiple placement policies with the
+	// same weight match a pod
+	ReasonPlacementPolicyConflict = "PlacementPolicyConflict"
+	// ReasonPlacementPolicyFilteredAllNodes is the event reason when a Strict placement policy
+	// filters all the nodes for a pod
+	ReasonPlacementPolicyFilteredAllNodes = "PlacementPolicyFilteredAllNodes"
+	// ReasonPlacementPolicyPreempted is the event reason when pods are preempted for a pod of a
+	// Strict placement policy
+	ReasonPlacementPolicyPreempted = "PlacementPolicyPreempted"
+
+	// eventActionScheduling is the action of the events recorded by the plugin
+	eventActionScheduling = "Scheduling"
+)
+
+// recordPodEvent records an event regarding the pod, related to the placement policy
+func (p *Plugin) recordPodEvent(pod *corev1.Pod, pp *v1alpha1.PlacementPolicy, eventtype, reason, note string, args ...interface{}) {
+	if p.eventRecorder == nil {
+		return
+	}
+	p.eventRecorder.Eventf(pod, policyObjectForEvent(pp), eventtype, reason, eventActionScheduling, note, args...)
+}
+
+// recordPodAndPolicyEvents records an event regarding the pod and an event regarding the placement policy
+func (p *Plugin) recordPodAndPolicyEvents(pod *corev1.Pod, pp *v1alpha1.PlacementPolicy, eventtype, reason, note string, args ...interface{}) {
+	if p.eventRecorder == nil {
+		return
+	}
+	policy := policyObjectForEvent(pp)
+	p.eventRecorder.Eventf(pod, policy, eventtype, reason, eventActionScheduling, note, args...)
+	p.eventRecorder.Eventf(policy, pod, eventtype, reason, eventActionScheduling, note, args...)
+}
+
+// policyObjectForEvent returns the object events related to the placement policy are recorded
+// on. The type meta is set as the placement policy types are not registered in the scheme of
+// the event recorder. Placement policies derived from a cluster placement policy are not stored,
+// so the events are recorded on the cluster placement policy.
+func policyObjectForEvent(pp *v1alpha1.PlacementPolicy) runtime.Object {
+	if owner := metav1.GetControllerOf(pp); owner != nil && owner.Kind == "ClusterPlacementPolicy" {
+		return &v1alpha1.ClusterPlacementPolicy{
+			TypeMeta:   metav1.TypeMeta{APIVersion: owner.APIVersion, Kind: owner.Kind},
+			ObjectMeta: metav1.ObjectMeta{Name: owner.Name, UID: owner.UID},
+		}
+	}
+	return &v1alpha1.PlacementPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "PlacementPolicy"},
+		ObjectMeta: *pp.ObjectMeta.DeepCopy(),
+	}
+}
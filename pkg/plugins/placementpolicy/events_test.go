@@ -0,0 +1,201 @@
+package placementpolicy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+func TestReserveEvents(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"node": "want"}}},
+	}
+	fh, err := frameworkruntime.NewFramework(nil, nil, frameworkruntime.WithSnapshotSharedLister(newFakeSharedLister(nodes)))
+	if err != nil {
+		t.Fatalf("NewFramework() error = %v", err)
+	}
+	nodeSelector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}})
+	if err != nil {
+		t.Fatalf("LabelSelectorAsSelector() error = %v", err)
+	}
+	pp := &v1alpha1.PlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "pp1", Namespace: "default"},
+		Spec:       v1alpha1.PlacementPolicySpec{Weight: 200, EnforcementMode: v1alpha1.EnforcementModeBestEffort},
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: types.UID("pod1")}}
+
+	tests := []struct {
+		name                string
+		conflictingPolicies []string
+		want                []string
+	}{
+		{
+			name: "placement policy applied",
+			want: []string{
+				"Normal PlacementPolicyApplied BestEffort placement policy pp1 applied, reserved on node node1 (matches the node selector: true)",
+			},
+		},
+		{
+			name:                "placement policies with the same weight",
+			conflictingPolicies: []string{"pp2"},
+			want: []string{
+				"Warning PlacementPolicyConflict Placement policy pp1 was applied to pod default/pod1 over placement policies [pp2] with the same weight 200",
+				"Warning PlacementPolicyConflict Placement policy pp1 was applied to pod default/pod1 over placement policies [pp2] with the same weight 200",
+				"Normal PlacementPolicyApplied BestEffort placement policy pp1 applied, reserved on node node1 (matches the node selector: true)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := events.NewFakeRecorder(10)
+			p := &Plugin{
+				frameworkHandler:  fh,
+				assumedPlacements: core.NewAssumedPlacementCache(),
+				eventRecorder:     recorder,
+			}
+			state := framework.NewCycleState()
+			state.Write(p.getPreScoreStateKey(), NewStateData(pod.Name, pp, nodeSelector, true, framework.MaxNodeScore, tt.conflictingPolicies, nil))
+
+			if status := p.Reserve(context.Background(), state, pod, "node1"); !status.IsSuccess() {
+				t.Fatalf("Reserve() status = %v", status)
+			}
+			if got := readEvents(recorder); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Reserve() events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostFilter(t *testing.T) {
+	nodeSelector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}})
+	if err != nil {
+		t.Fatalf("LabelSelectorAsSelector() error = %v", err)
+	}
+	pp := &v1alpha1.PlacementPolicy{ObjectMeta: metav1.ObjectMeta{Name: "pp1", Namespace: "default"}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"}}
+	filteredBy := func(plugin string) *framework.Status {
+		return framework.NewStatus(framework.Unschedulable).WithFailedPlugin(plugin)
+	}
+
+	tests := []struct {
+		name                  string
+		strict                bool
+		filteredNodeStatusMap framework.NodeToStatusMap
+		want                  []string
+	}{
+		{
+			name:   "all nodes filtered by the placement policy",
+			strict: true,
+			filteredNodeStatusMap: framework.NodeToStatusMap{
+				"node1": filteredBy(Name),
+				"node2": filteredBy(Name),
+			},
+			want: []string{
+				"Warning PlacementPolicyFilteredAllNodes Strict placement policy pp1 filtered all 2 nodes for pod default/pod1 (prefer nodes matching the node selector: true)",
+				"Warning PlacementPolicyFilteredAllNodes Strict placement policy pp1 filtered all 2 nodes for pod default/pod1 (prefer nodes matching the node selector: true)",
+			},
+		},
+		{
+			name:   "nodes filtered by other plugins",
+			strict: true,
+			filteredNodeStatusMap: framework.NodeToStatusMap{
+				"node1": filteredBy(Name),
+				"node2": filteredBy("NodeResourcesFit"),
+			},
+		},
+		{
+			name: "no strict placement policy",
+			filteredNodeStatusMap: framework.NodeToStatusMap{
+				"node1": filteredBy("NodeResourcesFit"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := events.NewFakeRecorder(10)
+			p := &Plugin{eventRecorder: recorder}
+			state := framework.NewCycleState()
+			if tt.strict {
+				state.Write(p.getPreFilterStateKey(), NewStateData(pod.Name, pp, nodeSelector, true, framework.MaxNodeScore, nil, nil))
+			}
+
+			_, status := p.PostFilter(context.Background(), state, pod, tt.filteredNodeStatusMap)
+			if status.Code() != framework.Unschedulable {
+				t.Errorf("PostFilter() status = %v, want %v", status.Code(), framework.Unschedulable)
+			}
+			if got := readEvents(recorder); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PostFilter() events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyObjectForEvent(t *testing.T) {
+	cpp := &v1alpha1.ClusterPlacementPolicy{ObjectMeta: metav1.ObjectMeta{Name: "cpp", UID: types.UID("cpp-uid")}}
+	pp := &v1alpha1.PlacementPolicy{ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "default", UID: types.UID("pp-uid")}}
+
+	tests := []struct {
+		name     string
+		pp       *v1alpha1.PlacementPolicy
+		wantKind string
+		wantName string
+		wantUID  types.UID
+	}{
+		{
+			name:     "placement policy",
+			pp:       pp,
+			wantKind: "PlacementPolicy",
+			wantName: "pp",
+			wantUID:  "pp-uid",
+		},
+		{
+			name:     "placement policy derived from a cluster placement policy",
+			pp:       cpp.PlacementPolicyForNamespace("default"),
+			wantKind: "ClusterPlacementPolicy",
+			wantName: "cpp",
+			wantUID:  "cpp-uid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := policyObjectForEvent(tt.pp)
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				t.Fatalf("meta.Accessor() error = %v", err)
+			}
+			if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != tt.wantKind {
+				t.Errorf("policyObjectForEvent() kind = %s, want %s", kind, tt.wantKind)
+			}
+			if accessor.GetName() != tt.wantName || accessor.GetUID() != tt.wantUID {
+				t.Errorf("policyObjectForEvent() = %s/%s, want %s/%s", accessor.GetName(), accessor.GetUID(), tt.wantName, tt.wantUID)
+			}
+		})
+	}
+}
+
+// readEvents returns the events recorded so far by the fake recorder
+func readEvents(recorder *events.FakeRecorder) []string {
+	var got []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			got = append(got, event)
+		default:
+			return got
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package placementpolicy
+
+import (
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// computeNodeGroupStateData determines the node group the pod should be placed on, from the number
+// of pods counted by the placement policy on the nodes of each node group
+func (p *Plugin) computeNodeGroupStateData(pod *corev1.Pod, pp *v1alpha1.PlacementPolicy, conflictingPolicies []string, nodeList []*corev1.Node, podList []*corev1.Pod) (framework.StateData, error) {
+	groupSelectors, err := core.GetNodeGroupSelectors(pp)
+	if err != nil {
+		return nil, err
+	}
+	groups := len(groupSelectors)
+
+	// the pods are counted on the node they are bound or reserved on, so the pods on nodes
+	// filtered for this pod still count. The pods on the nodes outside of the groups are
+	// counted last.
+	nodeInfos := p.frameworkHandler.SnapshotSharedLister().NodeInfos()
+	podsInGroup := make([][]*corev1.Pod, groups+1)
+	for _, other := range podList {
+		// this scheduling cycle is for the current pod on a node, we should skip it
+		if other.UID == pod.UID {
+			continue
+		}
+		nodeName := getPodNodeName(other, p.assumedPlacements)
+		if nodeName == "" {
+			continue
+		}
+		nodeInfo, err := nodeInfos.Get(nodeName)
+		if err != nil || nodeInfo.Node() == nil {
+			continue
+		}
+		group := core.GetNodeGroup(nodeInfo.Node(), groupSelectors)
+		if group < 0 {
+			group = groups
+		}
+		podsInGroup[group] = append(podsInGroup[group], other)
+	}
+	d := &stateData{
+		name:                pod.Name,
+		pp:                  pp,
+		conflictingPolicies: conflictingPolicies,
+		groupSelectors:      groupSelectors,
+	}
+	d.counts = newPlacementCounts(pod, podList, podsInGroup, p.getTopologySpreads(d, nodeList, podsInGroup), p.scoreMagnitude)
+	if err := d.updatePreference(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// getNodeGroupPreference returns the index of the node group the pod should be placed on, or
+// len(groups) for the nodes outside of the groups, and the number of pods the group is below its
+// target. podsInGroup are the numbers of pods on the nodes of each group, followed by the number of
+// pods on the nodes outside of the groups. The group furthest below its target, as computed by
+// core.GetNodeGroupTargets, is preferred, ties going to the first group.
+func getNodeGroupPreference(groups []v1alpha1.NodeGroup, podsInGroup []int, totalPods int) (int, int, error) {
+	// the targets and deficits are in hundredths of a pod
+	targets, err := core.GetNodeGroupTargets(groups, totalPods)
+	if err != nil {
+		return 0, 0, err
+	}
+	preferred, maxDeficit := 0, 0
+	for i, target := range targets {
+		if deficit := target - 100*podsInGroup[i]; i == 0 || deficit > maxDeficit {
+			preferred, maxDeficit = i, deficit
+		}
+	}
+
+	// round the deficit up to whole pods
+	deficit := maxDeficit / 100
+	if maxDeficit%100 > 0 {
+		deficit++
+	}
+	return preferred, deficit, nil
+}
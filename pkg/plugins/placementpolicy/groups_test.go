@@ -0,0 +1,89 @@
+package placementpolicy
+
+import (
+	"testing"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestGetNodeGroupPreference(t *testing.T) {
+	makeGroups := func(targetSizes ...intstr.IntOrString) []v1alpha1.NodeGroup {
+		groups := make([]v1alpha1.NodeGroup, 0, len(targetSizes))
+		for i := range targetSizes {
+			groups = append(groups, v1alpha1.NodeGroup{Name: string(rune('a' + i)), TargetSize: &targetSizes[i]})
+		}
+		return groups
+	}
+	percentages := makeGroups(intstr.FromString("50%"), intstr.FromString("30%"), intstr.FromString("20%"))
+
+	tests := []struct {
+		name          string
+		groups        []v1alpha1.NodeGroup
+		podsInGroup   []int
+		totalPods     int
+		wantPreferred int
+		wantDeficit   int
+	}{
+		{
+			name:          "no pods placed",
+			groups:        percentages,
+			podsInGroup:   []int{0, 0, 0, 0},
+			totalPods:     10,
+			wantPreferred: 0,
+			wantDeficit:   5,
+		},
+		{
+			name:          "group furthest below its target",
+			groups:        percentages,
+			podsInGroup:   []int{5, 0, 1, 0},
+			totalPods:     10,
+			wantPreferred: 1,
+			wantDeficit:   3,
+		},
+		{
+			name:          "all groups at their target",
+			groups:        percentages,
+			podsInGroup:   []int{5, 3, 2, 0},
+			totalPods:     10,
+			wantPreferred: 0,
+			wantDeficit:   0,
+		},
+		{
+			name:          "nodes outside of the groups get the remaining pods",
+			groups:        makeGroups(intstr.FromString("50%"), intstr.FromString("20%")),
+			podsInGroup:   []int{5, 2, 0},
+			totalPods:     10,
+			wantPreferred: 2,
+			wantDeficit:   3,
+		},
+		{
+			name:          "targets clamped to the total number of pods",
+			groups:        makeGroups(intstr.FromInt(8), intstr.FromInt(5)),
+			podsInGroup:   []int{8, 0, 0},
+			totalPods:     10,
+			wantPreferred: 1,
+			wantDeficit:   2,
+		},
+		{
+			name:          "fractional targets",
+			groups:        makeGroups(intstr.FromString("50%"), intstr.FromString("50%")),
+			podsInGroup:   []int{2, 0, 0},
+			totalPods:     3,
+			wantPreferred: 1,
+			wantDeficit:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preferred, deficit, err := getNodeGroupPreference(tt.groups, tt.podsInGroup, tt.totalPods)
+			if err != nil {
+				t.Fatalf("getNodeGroupPreference() error = %v", err)
+			}
+			if preferred != tt.wantPreferred || deficit != tt.wantDeficit {
+				t.Errorf("getNodeGroupPreference() = %d, %d, want %d, %d", preferred, deficit, tt.wantPreferred, tt.wantDeficit)
+			}
+		})
+	}
+}
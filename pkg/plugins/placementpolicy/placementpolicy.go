@@ -3,22 +3,30 @@ package placementpolicy
 import (
 	"context"
 	"fmt"
-	"math"
-	"strconv"
 	"sync"
+	"time"
 
+	configscheme "github.com/Azure/placement-policy-scheduler-plugins/apis/config/scheme"
+	configv1beta1 "github.com/Azure/placement-policy-scheduler-plugins/apis/config/v1beta1"
 	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
 	ppclientset "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/clientset/versioned"
 	ppinformers "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/informers/externalversions"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/metrics"
 	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/kubernetes"
+	policylisters "k8s.io/client-go/listers/policy/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
 )
 
 // Plugin is a plugin that schedules pods on nodes based on
@@ -27,41 +35,96 @@ type Plugin struct {
 	sync.RWMutex
 	frameworkHandler framework.Handle
 	ppMgr            core.Manager
+	// assumedPlacements tracks the node group pods were reserved on until
+	// they are bound to the node
+	assumedPlacements *core.AssumedPlacementCache
+	// annotatePods enables asynchronously writing the node preference to the
+	// pod annotations for visibility
+	annotatePods bool
+	// scoreMagnitude is the score of the nodes of the preferred group when the
+	// placement of the pods is the furthest from the target size
+	scoreMagnitude int64
+	// enablePreemption enables preempting pods on the nodes of the preferred group
+	// when a Strict placement policy leaves the pod unschedulable
+	enablePreemption bool
+	// eventRecorder records the events regarding the pods and the placement policies
+	eventRecorder events.EventRecorder
+	// pdbLister lists the pod disruption budgets of the preemption victims, nil if preemption is disabled
+	pdbLister policylisters.PodDisruptionBudgetLister
+	// counters count incrementally the pods of the placement policies, nil if the pods
+	// are always counted from the full list of the pods of the placement policy
+	counters *core.PlacementCounters
 }
 
 const (
 	// Name is the plugin name
 	Name = "placementpolicy"
+
+	// annotatePodTimeout is the timeout of the asynchronous pod annotation patch
+	annotatePodTimeout = 30 * time.Second
 )
 
 var _ framework.PreFilterPlugin = &Plugin{}
 var _ framework.FilterPlugin = &Plugin{}
+var _ framework.PostFilterPlugin = &Plugin{}
 var _ framework.PreScorePlugin = &Plugin{}
 var _ framework.ScorePlugin = &Plugin{}
+var _ framework.ReservePlugin = &Plugin{}
+var _ framework.EnqueueExtensions = &Plugin{}
+
+var (
+	// placementPolicyGVK and clusterPlacementPolicyGVK are the resources of the placement
+	// policies, in the <plural>.<version>.<group> format of the scheduler dynamic informers
+	placementPolicyGVK        = framework.GVK(fmt.Sprintf("placementpolicies.%s.%s", v1alpha1.GroupVersion.Version, v1alpha1.GroupVersion.Group))
+	clusterPlacementPolicyGVK = framework.GVK(fmt.Sprintf("clusterplacementpolicies.%s.%s", v1alpha1.GroupVersion.Version, v1alpha1.GroupVersion.Group))
+)
 
 // New initializes and returns a new PlacementPolicy plugin.
 func New(obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+	args, err := getArgs(obj)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Register()
 
 	client := kubernetes.NewForConfigOrDie(handle.KubeConfig())
 	ppClient := ppclientset.NewForConfigOrDie(handle.KubeConfig())
-	ppInformerFactory := ppinformers.NewSharedInformerFactory(ppClient, 0)
+	ppInformerFactory := ppinformers.NewSharedInformerFactory(ppClient, args.ResyncPeriod.Duration)
 	ppInformer := ppInformerFactory.Placementpolicy().V1alpha1().PlacementPolicies()
+	cppInformer := ppInformerFactory.Placementpolicy().V1alpha1().ClusterPlacementPolicies()
 
 	ppMgr := core.NewPlacementPolicyManager(
 		client,
 		ppClient,
 		handle.SnapshotSharedLister(),
 		ppInformer,
-		handle.SharedInformerFactory().Core().V1().Pods().Lister())
-
-	plugin := &Plugin{
-		frameworkHandler: handle,
-		ppMgr:            ppMgr,
-	}
+		cppInformer,
+		handle.SharedInformerFactory().Core().V1().Pods().Lister(),
+		handle.SharedInformerFactory().Core().V1().Namespaces().Lister(),
+		*args.DefaultEnforcementMode)
+
+	plugin := NewPlugin(handle, ppMgr, args)
+	if plugin.enablePreemption {
+		plugin.pdbLister = handle.SharedInformerFactory().Policy().V1().PodDisruptionBudgets().Lister()
+	}
+	plugin.counters = core.NewPlacementCounters()
+	handle.SharedInformerFactory().Core().V1().Pods().Informer().AddEventHandler(plugin.counters.PodEventHandler())
+	handle.SharedInformerFactory().Core().V1().Nodes().Informer().AddEventHandler(plugin.counters.NodeEventHandler())
+
+	// once a pod is bound or deleted, it's counted from the pod lister and the
+	// assumed placement is no longer needed
+	handle.SharedInformerFactory().Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, newObj interface{}) {
+			if pod, ok := newObj.(*corev1.Pod); ok && pod.Spec.NodeName != "" {
+				plugin.assumedPlacements.Forget(pod.UID)
+			}
+		},
+		DeleteFunc: plugin.forgetPod,
+	})
 
 	ctx := context.Background()
 	ppInformerFactory.Start(ctx.Done())
-	if !cache.WaitForCacheSync(ctx.Done(), ppInformer.Informer().HasSynced) {
+	if !cache.WaitForCacheSync(ctx.Done(), ppInformer.Informer().HasSynced, cppInformer.Informer().HasSynced) {
 		err := fmt.Errorf("WaitForCacheSync failed")
 		klog.ErrorS(err, "Cannot sync caches")
 		return nil, err
@@ -70,19 +133,67 @@ func New(obj runtime.Object, handle framework.Handle) (framework.Plugin, error)
 	return plugin, nil
 }
 
+// NewPlugin returns a PlacementPolicy plugin looking up the placement policies with the given
+// manager. Unlike New, it doesn't create any client or informer, so it can be used to run the
+// plugin outside of the scheduler. The arguments must be defaulted.
+func NewPlugin(handle framework.Handle, ppMgr core.Manager, args *configv1beta1.PlacementPolicyArgs) *Plugin {
+	return &Plugin{
+		frameworkHandler:  handle,
+		ppMgr:             ppMgr,
+		assumedPlacements: core.NewAssumedPlacementCache(),
+		annotatePods:      *args.AnnotatePods,
+		scoreMagnitude:    *args.ScoreMagnitude,
+		enablePreemption:  *args.EnablePreemption,
+		eventRecorder:     handle.EventRecorder(),
+	}
+}
+
+// getArgs returns the defaulted and validated arguments of the plugin. The arguments are
+// passed as runtime.Unknown when they are set in the pluginConfig of the scheduler profile,
+// or are nil when they are not set.
+func getArgs(obj runtime.Object) (*configv1beta1.PlacementPolicyArgs, error) {
+	args := &configv1beta1.PlacementPolicyArgs{}
+	switch a := obj.(type) {
+	case nil:
+	case *configv1beta1.PlacementPolicyArgs:
+		args = a.DeepCopy()
+	default:
+		if err := frameworkruntime.DecodeInto(obj, args); err != nil {
+			return nil, fmt.Errorf("failed to decode %s args: %w", Name, err)
+		}
+	}
+	configscheme.Scheme.Default(args)
+	if err := configv1beta1.ValidatePlacementPolicyArgs(field.NewPath("args"), args); err != nil {
+		return nil, fmt.Errorf("invalid %s args: %w", Name, err)
+	}
+	return args, nil
+}
+
 // Name returns name of the plugin. It is used in logs, etc.
 func (p *Plugin) Name() string {
 	return Name
 }
 
+// EventsToRegister returns the cluster events that may make the pods rejected by the plugin
+// schedulable: a node added or relabeled into the preferred group, a pod of the placement policy
+// deleted, which changes the node preference, or a placement policy created, updated or deleted.
+func (p *Plugin) EventsToRegister() []framework.ClusterEvent {
+	return []framework.ClusterEvent{
+		{Resource: framework.Node, ActionType: framework.Add | framework.UpdateNodeLabel},
+		{Resource: framework.Pod, ActionType: framework.Delete},
+		{Resource: placementPolicyGVK, ActionType: framework.All},
+		{Resource: clusterPlacementPolicyGVK, ActionType: framework.All},
+	}
+}
+
 // PreFilter performs the following.
 // 1. Whether there is a placement policy for the pod.
 // 2. Whether the placement policy is Strict.
 // 3. Determines the node preference for the pod: node with labels matching placement policy or other
-// 4. Annotate the pod with the node preference and the placement policy.
+// 4. Writes the node preference and the placement policy to the cycle state.
 func (p *Plugin) PreFilter(ctx context.Context, state *framework.CycleState, pod *corev1.Pod) *framework.Status {
 	// get the placement policy that matches pod
-	pp, err := p.ppMgr.GetPlacementPolicyForPod(ctx, pod)
+	pp, conflictingPolicies, err := p.getPlacementPolicyForPod(ctx, pod)
 	if err != nil {
 		return framework.NewStatus(framework.Error, fmt.Sprintf("failed to get placement policy for pod %s: %v", pod.Name, err))
 	}
@@ -91,62 +202,61 @@ func (p *Plugin) PreFilter(ctx context.Context, state *framework.CycleState, pod
 		klog.InfoS("no placement policy found for pod", "pod", pod.Name)
 		return framework.NewStatus(framework.Success, "")
 	}
+	// PreFilter runs once per scheduling attempt for both enforcement modes
+	metrics.PodsEvaluated.WithLabelValues(pp.Namespace, pp.Name).Inc()
 	// skip filtering if the enforcement mode is best effort
 	// only filter if the enforcement mode is strict
 	if pp.Spec.EnforcementMode == v1alpha1.EnforcementModeBestEffort {
 		return framework.NewStatus(framework.Success, "")
 	}
-	nodeInfoList, err := p.frameworkHandler.SnapshotSharedLister().NodeInfos().List()
+	nodeList, err := p.getNodeList()
 	if err != nil {
-		return framework.NewStatus(framework.Error, fmt.Sprintf("failed to get nodes in the cluster: %v", err))
+		return framework.NewStatus(framework.Error, err.Error())
 	}
-	nodeList := make([]*corev1.Node, 0, len(nodeInfoList))
-	for _, nodeInfo := range nodeInfoList {
-		nodeList = append(nodeList, nodeInfo.Node())
-	}
-
-	// nodeWithMatchingLabels is a group of nodes that have the same labels as defined in the placement policy
-	nodeWithMatchingLabels := groupNodesWithLabels(nodeList, pp.Spec.NodeSelector.MatchLabels)
 
-	podList, err := p.ppMgr.GetPodsWithLabels(ctx, pp.Spec.PodSelector.MatchLabels)
+	data, err := p.computeStateData(ctx, pod, pp, conflictingPolicies, nodeList)
 	if err != nil {
-		return framework.NewStatus(framework.Error, fmt.Sprintf("failed to get pods with labels: %v", err))
+		return framework.NewStatus(framework.Error, err.Error())
 	}
+	state.Write(p.getPreFilterStateKey(), data)
+	return framework.NewStatus(framework.Success, "")
+}
 
-	// podsOnNodeWithMatchingLabels is a group of pods with matching pod labels defined in placement policy
-	// that are already on the nodes with matching labels or annotated to be on the nodes with matching node labels
-	// by the placement policy scheduler plugin
-	podsOnNodeWithMatchingLabels := len(groupPodsBasedOnNodePreference(podList, pod, nodeWithMatchingLabels))
+// PreFilterExtensions returns the plugin, which updates the node preference of the pod when the
+// preemption adds or removes the pods counted by the placement policy.
+func (p *Plugin) PreFilterExtensions() framework.PreFilterExtensions {
+	return p
+}
 
-	targetSize, err := intstr.GetScaledValueFromIntOrPercent(pp.Spec.Policy.TargetSize, len(podList), false)
+// AddPod counts the pod added to the node in the node preference of the pod being scheduled,
+// if it's counted by the Strict placement policy of the pod.
+func (p *Plugin) AddPod(ctx context.Context, state *framework.CycleState, podToSchedule *corev1.Pod, podInfoToAdd *framework.PodInfo, nodeInfo *framework.NodeInfo) *framework.Status {
+	d, err := p.readPreFilterStateData(state)
 	if err != nil {
-		return framework.NewStatus(framework.Error, fmt.Sprintf("failed to get scaled value from int or percent: %v", err))
+		return framework.NewStatus(framework.Error, err.Error())
 	}
-	// if the action is mustnot, we'll use the inverse of the target size against total pods
-	// to compute number of pods on nodes with matching labels
-	if pp.Spec.Policy.Action == v1alpha1.ActionMustNot {
-		targetSize = len(podList) - targetSize
+	if d == nil || nodeInfo.Node() == nil {
+		return nil
 	}
-
-	preferredNodeWithMatchingLabels := false
-	// if the number of pods on the node with matching labels is less than the target size, then we should prefer the node
-	if podsOnNodeWithMatchingLabels < targetSize {
-		preferredNodeWithMatchingLabels = true
+	if err := d.addPod(podInfoToAdd.Pod, nodeInfo.Node()); err != nil {
+		return framework.AsStatus(err)
 	}
+	return nil
+}
 
-	klog.InfoS("annotating pod", "pod", pod.Name, "plugin", "prefilter")
-	// annotate pod with placement policy
-	pod, err = p.ppMgr.AnnotatePod(ctx, pod, pp, preferredNodeWithMatchingLabels)
+// RemovePod stops counting the pod removed from the node in the node preference of the pod being
+// scheduled, if it's counted by the Strict placement policy of the pod.
+func (p *Plugin) RemovePod(ctx context.Context, state *framework.CycleState, podToSchedule *corev1.Pod, podInfoToRemove *framework.PodInfo, nodeInfo *framework.NodeInfo) *framework.Status {
+	d, err := p.readPreFilterStateData(state)
 	if err != nil {
-		return framework.NewStatus(framework.Error, fmt.Sprintf("failed to annotate pod %s: %v", pod.Name, err))
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+	if d == nil || nodeInfo.Node() == nil {
+		return nil
+	}
+	if err := d.removePod(podInfoToRemove.Pod, nodeInfo.Node()); err != nil {
+		return framework.AsStatus(err)
 	}
-
-	state.Write(p.getPreFilterStateKey(), NewStateData(pod.Name, pp))
-	return framework.NewStatus(framework.Success, "")
-}
-
-// PreFilterExtensions returns a PreFilterExtensions interface if the plugin implements one.
-func (p *Plugin) PreFilterExtensions() framework.PreFilterExtensions {
 	return nil
 }
 
@@ -171,35 +281,73 @@ func (p *Plugin) Filter(ctx context.Context, state *framework.CycleState, pod *c
 	}
 
 	node := nodeInfo.Node()
-	// nodeMatchesLabels is set to true if the node in the current context matches the node selector labels
-	// defined in the placement policy chosen for the pod.
-	nodeMatchesLabels := checkHasLabels(node.Labels, d.pp.Spec.NodeSelector.MatchLabels)
+	// if the node preference of the pod matches the node group in the current context, then don't filter the node
+	// unless placing the pod on it would unbalance the topology domains of the group
+	if d.inPreferredGroup(node) {
+		if d.topologySpread == nil || d.topologySpread.fits(node) {
+			return framework.NewStatus(framework.Success, "")
+		}
+		klog.InfoS("filtering node", "node", node.Name, "pod", pod.Name, "topologyKey", d.topologySpread.topologyKey)
+		metrics.FilterRejections.WithLabelValues(d.pp.Namespace, d.pp.Name).Inc()
+		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("node(s) didn't match the %s spread of placement policy %s", d.topologySpread.topologyKey, d.pp.Name))
+	}
 
-	// podNodePreferMatchingLabels is set to true if the pod is annotated to be on the node with matching labels
-	podNodePreferMatchingLabels := false
-	if podNodePreferMatchingLabels, err = strconv.ParseBool(pod.Annotations[v1alpha1.PlacementPolicyPreferenceAnnotationKey]); err != nil {
-		return framework.NewStatus(framework.Error, fmt.Sprintf("failed to parse pod annotation: %v", err))
+	klog.InfoS("filtering node", "node", node.Name, "pod", pod.Name)
+	metrics.FilterRejections.WithLabelValues(d.pp.Namespace, d.pp.Name).Inc()
+	return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("node(s) didn't match the node preference of placement policy %s", d.pp.Name))
+}
+
+// PostFilter is called when no node fits the pod. It records an event on the pod and the placement
+// policy if all the nodes were filtered by the Strict placement policy of the pod. If preemption is
+// enabled, it preempts pods on a node of the preferred group for the pod to fit on it.
+func (p *Plugin) PostFilter(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, filteredNodeStatusMap framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
+	data, err := state.Read(p.getPreFilterStateKey())
+	if err != nil {
+		// no Strict placement policy for the pod
+		return nil, framework.NewStatus(framework.Unschedulable)
+	}
+	d, ok := data.(*stateData)
+	if !ok {
+		return nil, framework.NewStatus(framework.Error, "failed to cast state data")
 	}
 
-	// if the node preference annotation on the pod matches the node group in the current context, then don't filter the node
-	if nodeMatchesLabels && podNodePreferMatchingLabels ||
-		!nodeMatchesLabels && !podNodePreferMatchingLabels {
-		return framework.NewStatus(framework.Success, "")
+	if len(filteredNodeStatusMap) == 0 {
+		return nil, framework.NewStatus(framework.Unschedulable)
+	}
+	filteredAllNodes := true
+	for _, status := range filteredNodeStatusMap {
+		if status.FailedPlugin() != p.Name() {
+			filteredAllNodes = false
+			break
+		}
+	}
+	if filteredAllNodes {
+		p.recordPodAndPolicyEvents(pod, d.pp, corev1.EventTypeWarning, ReasonPlacementPolicyFilteredAllNodes,
+			"Strict placement policy %s filtered all %d nodes for pod %s/%s (prefer nodes matching the node selector: %t)",
+			d.pp.Name, len(filteredNodeStatusMap), pod.Namespace, pod.Name, d.preferredNodeWithMatchingLabels)
+	}
+	if !p.enablePreemption {
+		return nil, framework.NewStatus(framework.Unschedulable)
 	}
 
-	klog.InfoS("filtering node", "node", node.Name, "pod", pod.Name)
-	return framework.NewStatus(framework.Unschedulable, "")
+	nodeName, err := p.preempt(ctx, state, pod, d, filteredNodeStatusMap)
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	if nodeName == "" {
+		return nil, framework.NewStatus(framework.Unschedulable)
+	}
+	return &framework.PostFilterResult{NominatedNodeName: nodeName}, framework.NewStatus(framework.Success)
 }
 
 // PreScore performs the following.
 // 1. Whether there is a placement policy for the pod.
 // 2. Whether the placement policy is BestEffort.
 // 3. Determines the node preference for the pod: node with labels matching placement policy or other
-// 4. Annotate the pod with the node preference and the placement policy.
-func (p *Plugin) PreScore(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, nodes []*corev1.Node) *framework.Status {
-	// TODO(aramase) refactor as there is duplicate code in PreFilter and PreScore
+// 4. Writes the node preference and the placement policy to the cycle state.
+func (p *Plugin) PreScore(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, _ []*corev1.Node) *framework.Status {
 	// get the placement policy that matches pod
-	pp, err := p.ppMgr.GetPlacementPolicyForPod(ctx, pod)
+	pp, conflictingPolicies, err := p.getPlacementPolicyForPod(ctx, pod)
 	if err != nil {
 		return framework.NewStatus(framework.Error, fmt.Sprintf("failed to get placement policy for pod %s: %v", pod.Name, err))
 	}
@@ -212,44 +360,155 @@ func (p *Plugin) PreScore(ctx context.Context, state *framework.CycleState, pod
 		return framework.NewStatus(framework.Success, "")
 	}
 
-	// nodeWithMatchingLabels is a group of nodes that have the same labels as defined in the placement policy
-	nodeWithMatchingLabels := groupNodesWithLabels(nodes, pp.Spec.NodeSelector.MatchLabels)
+	// the pods of the placement policy are counted on all the nodes, not only on the nodes
+	// left after filtering for this pod
+	nodeList, err := p.getNodeList()
+	if err != nil {
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+
+	data, err := p.computeStateData(ctx, pod, pp, conflictingPolicies, nodeList)
+	if err != nil {
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+	state.Write(p.getPreScoreStateKey(), data)
+	return framework.NewStatus(framework.Success, "")
+}
+
+// getNodeList returns all the nodes in the scheduler snapshot.
+func (p *Plugin) getNodeList() ([]*corev1.Node, error) {
+	nodeInfoList, err := p.frameworkHandler.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes in the cluster: %v", err)
+	}
+	nodeList := make([]*corev1.Node, 0, len(nodeInfoList))
+	for _, nodeInfo := range nodeInfoList {
+		nodeList = append(nodeList, nodeInfo.Node())
+	}
+	return nodeList, nil
+}
+
+// computeStateData determines the node preference of the pod from the placement of the other pods
+// counted by the placement policy, among the nodes of nodeList, which holds all the nodes in the cluster.
+func (p *Plugin) computeStateData(ctx context.Context, pod *corev1.Pod, pp *v1alpha1.PlacementPolicy, conflictingPolicies []string, nodeList []*corev1.Node) (framework.StateData, error) {
+	var counted *stateData
+	if p.counters != nil && core.CountersSupported(pp) {
+		d, check, err := p.computeCountedStateData(pod, pp, conflictingPolicies)
+		if err != nil {
+			return nil, err
+		}
+		if !check {
+			return d, nil
+		}
+		counted = d
+	}
+
+	podList, err := p.ppMgr.GetPodsForPlacementPolicy(ctx, pp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods for placement policy: %v", err)
+	}
+	// with the PerOwner scope, only the pods of the same workload as the pod are counted
+	podList = core.GetPodsInScope(pp, podList, pod)
+
+	if len(pp.Spec.Policy.Groups) > 0 {
+		return p.computeNodeGroupStateData(pod, pp, conflictingPolicies, nodeList, podList)
+	}
 
-	podList, err := p.ppMgr.GetPodsWithLabels(ctx, pp.Spec.PodSelector.MatchLabels)
+	nodeSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.NodeSelector)
 	if err != nil {
-		return framework.NewStatus(framework.Error, fmt.Sprintf("failed to get pods with labels: %v", err))
+		return nil, fmt.Errorf("failed to parse node selector: %v", err)
 	}
 
+	// nodeWithMatchingLabels is a group of nodes that have labels matching the node selector defined in the placement policy
+	nodeWithMatchingLabels := groupNodesWithLabels(nodeList, nodeSelector)
+
 	// podsOnNodeWithMatchingLabels is a group of pods with matching pod labels defined in placement policy
 	// that are already on the nodes with matching labels or annotated to be on the nodes with matching node labels
 	// by the placement policy scheduler plugin
-	podsOnNodeWithMatchingLabels := len(groupPodsBasedOnNodePreference(podList, pod, nodeWithMatchingLabels))
+	podsOnNodeWithMatchingLabels, podsOnOtherNodes := groupPodsBasedOnNodePreference(podList, pod, nodeWithMatchingLabels, p.assumedPlacements)
+
+	d := NewStateData(pod.Name, pp, nodeSelector, false, 0, conflictingPolicies, nil).(*stateData)
+	podsInGroup := [][]*corev1.Pod{podsOnNodeWithMatchingLabels, podsOnOtherNodes}
+	d.counts = newPlacementCounts(pod, podList, podsInGroup, p.getTopologySpreads(d, nodeList, podsInGroup), p.scoreMagnitude)
+	if err := d.updatePreference(); err != nil {
+		return nil, err
+	}
+	if counted != nil {
+		p.checkCounters(pod, counted, d)
+	}
+	return d, nil
+}
 
-	targetSize, err := intstr.GetScaledValueFromIntOrPercent(pp.Spec.Policy.TargetSize, len(podList), false)
+// computeCountedStateData determines the node preference of the pod from the placement counters,
+// instead of listing and grouping all the pods of the placement policy. It also returns whether the
+// counters are due to be checked against a full recount.
+func (p *Plugin) computeCountedStateData(pod *corev1.Pod, pp *v1alpha1.PlacementPolicy, conflictingPolicies []string) (*stateData, bool, error) {
+	count, check, err := p.counters.GetCount(pp)
 	if err != nil {
-		return framework.NewStatus(framework.Error, fmt.Sprintf("failed to get scaled value from int or percent: %v", err))
+		return nil, false, err
 	}
-	// if the action is mustnot, we'll use the inverse of the target size against total pods
-	// to compute number of pods on nodes with matching labels
-	if pp.Spec.Policy.Action == v1alpha1.ActionMustNot {
-		targetSize = len(podList) - targetSize
+	nodeSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.NodeSelector)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse node selector: %v", err)
 	}
 
-	preferredNodeWithMatchingLabels := false
-	// if the number of pods on the node with matching labels is less than the target size, then we should prefer the node
-	if podsOnNodeWithMatchingLabels < targetSize {
-		preferredNodeWithMatchingLabels = true
+	// the pods reserved on a node but not bound yet are counted on the node group of the assumed placement
+	podsOnNodeWithMatchingLabels, podsOnNodes := count.PodsOnNodeWithMatchingLabels, count.PodsOnNodes
+	for _, uid := range count.PendingPods {
+		if uid == pod.UID {
+			continue
+		}
+		placement, ok := p.assumedPlacements.Get(uid)
+		if !ok {
+			continue
+		}
+		podsOnNodes++
+		if placement.NodeWithMatchingLabels {
+			podsOnNodeWithMatchingLabels++
+		}
 	}
 
-	klog.InfoS("annotating pod", "pod", pod.Name, "plugin", "prefilter")
-	// annotate pod with placement policy
-	pod, err = p.ppMgr.AnnotatePod(ctx, pod, pp, preferredNodeWithMatchingLabels)
-	if err != nil {
-		return framework.NewStatus(framework.Error, fmt.Sprintf("failed to annotate pod %s: %v", pod.Name, err))
+	counters := p.counters
+	d := NewStateData(pod.Name, pp, nodeSelector, false, 0, conflictingPolicies, nil).(*stateData)
+	d.counts = &placementCounts{
+		podsInGroup: []int{podsOnNodeWithMatchingLabels, podsOnNodes - podsOnNodeWithMatchingLabels},
+		totalPods:   count.TotalPods,
+		placed: func(uid types.UID) (bool, bool) {
+			if uid == pod.UID {
+				return false, false
+			}
+			nodeName, ok := counters.GetPodNodeName(pp, uid)
+			if !ok {
+				return false, false
+			}
+			if nodeName != "" {
+				return true, true
+			}
+			_, assumed := p.assumedPlacements.Get(uid)
+			return assumed, true
+		},
+		changes:        make(map[types.UID]bool),
+		scoreMagnitude: p.scoreMagnitude,
+	}
+	if err := d.updatePreference(); err != nil {
+		return nil, false, err
 	}
+	return d, check, nil
+}
 
-	state.Write(p.getPreScoreStateKey(), NewStateData(pod.Name, pp))
-	return framework.NewStatus(framework.Success, "")
+// checkCounters compares the counts of the state computed from the placement counters with the
+// full recount of the pods of the placement policy. On a mismatch, the counters of the placement
+// policy are counted again from scratch the next time they are looked up. The counters can lag
+// behind the listers for the time the informer events are handled, so a rare mismatch is expected.
+func (p *Plugin) checkCounters(pod *corev1.Pod, counted, recounted *stateData) {
+	if counted.counts.totalPods == recounted.counts.totalPods && counted.counts.podsInGroup[0] == recounted.counts.podsInGroup[0] {
+		return
+	}
+	klog.ErrorS(nil, "placement counters don't match the pods of the placement policy", "placementPolicy", klog.KObj(counted.pp), "pod", klog.KObj(pod),
+		"countedPods", counted.counts.totalPods, "recountedPods", recounted.counts.totalPods,
+		"countedPodsOnNodeWithMatchingLabels", counted.counts.podsInGroup[0], "recountedPodsOnNodeWithMatchingLabels", recounted.counts.podsInGroup[0])
+	metrics.CounterMismatches.WithLabelValues(counted.pp.Namespace, counted.pp.Name).Inc()
+	p.counters.Reset(counted.pp)
 }
 
 // Score invoked at the score extension point.
@@ -272,57 +531,90 @@ func (p *Plugin) Score(ctx context.Context, state *framework.CycleState, pod *co
 		return 0, framework.NewStatus(framework.Error, fmt.Sprintf("getting node %q from Snapshot: %v", nodeName, err))
 	}
 	node := nodeInfo.Node()
-	// nodeMatchesLabels is set to true if the node in the current context matches the node selector labels
-	// defined in the placement policy chosen for the pod.
-	nodeMatchesLabels := checkHasLabels(node.Labels, d.pp.Spec.NodeSelector.MatchLabels)
-
-	// podNodePreferMatchingLabels is set to true if the pod is annotated to be on the node with matching labels
-	podNodePreferMatchingLabels := false
-	if podNodePreferMatchingLabels, err = strconv.ParseBool(pod.Annotations[v1alpha1.PlacementPolicyPreferenceAnnotationKey]); err != nil {
-		return 0, framework.NewStatus(framework.Error, fmt.Sprintf("failed to parse pod annotation: %v", err))
-	}
-
-	// if the node preference annotation on the pod matches the node group in the current context, then don't filter the node
-	if nodeMatchesLabels && podNodePreferMatchingLabels ||
-		!nodeMatchesLabels && !podNodePreferMatchingLabels {
-		return 100, nil
+	// if the node preference of the pod matches the node group in the current context, then score the node
+	// based on how far the placement of the pods is from the target size, and on the spread of the pods
+	// across the topology domains of the group
+	if d.inPreferredGroup(node) {
+		if d.topologySpread != nil {
+			return d.topologySpread.score(node, d.preferenceScore), nil
+		}
+		return d.preferenceScore, nil
 	}
 
-	return 0, nil
+	return framework.MinNodeScore, nil
 }
 
-// ScoreExtensions of the Score plugin.
+// ScoreExtensions of the Score plugin. The scores are already in the framework's
+// min to max node score range and are not normalized, so a small deviation from
+// the target size results in a weak preference.
 func (p *Plugin) ScoreExtensions() framework.ScoreExtensions {
-	return p
+	return nil
 }
 
-// NormalizeScore invoked after scoring all nodes.
-func (p *Plugin) NormalizeScore(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, scores framework.NodeScoreList) *framework.Status {
-	// Find highest and lowest scores.
-	var highest int64 = -math.MaxInt64
-	var lowest int64 = math.MaxInt64
-	for _, nodeScore := range scores {
-		if nodeScore.Score > highest {
-			highest = nodeScore.Score
-		}
-		if nodeScore.Score < lowest {
-			lowest = nodeScore.Score
-		}
+// Reserve records the node group the pod was reserved on in the assumed placement
+// cache, so the pod is counted by the scheduling cycles of other pods matching
+// the same placement policy until it's bound to the node.
+func (p *Plugin) Reserve(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, nodeName string) *framework.Status {
+	d, err := p.readStateData(state)
+	if err != nil {
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+	// no placement policy applied to the pod
+	if d == nil {
+		return framework.NewStatus(framework.Success, "")
 	}
+	nodeInfo, err := p.frameworkHandler.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	if err != nil {
+		return framework.NewStatus(framework.Error, fmt.Sprintf("getting node %q from Snapshot: %v", nodeName, err))
+	}
+
+	nodeMatchesLabels := d.nodeSelector.Matches(labels.Set(nodeInfo.Node().Labels))
+	p.assumedPlacements.Assume(pod.UID, core.AssumedPlacement{
+		PolicyName:             d.pp.Name,
+		NodeName:               nodeName,
+		NodeWithMatchingLabels: nodeMatchesLabels,
+	})
+	p.annotatePod(pod, d.pp, d.preferredNodeWithMatchingLabels)
+
+	if len(d.conflictingPolicies) > 0 {
+		p.recordPodAndPolicyEvents(pod, d.pp, corev1.EventTypeWarning, ReasonPlacementPolicyConflict,
+			"Placement policy %s was applied to pod %s/%s over placement policies %v with the same weight %d",
+			d.pp.Name, pod.Namespace, pod.Name, d.conflictingPolicies, d.pp.Spec.Weight)
+	}
+	if d.groupSelectors != nil {
+		p.recordPodEvent(pod, d.pp, corev1.EventTypeNormal, ReasonPlacementPolicyApplied,
+			"%s placement policy %s applied, reserved on node %s (node group: %s)",
+			d.pp.Spec.EnforcementMode, d.pp.Name, nodeName, d.nodeGroupName(nodeInfo.Node()))
+		return framework.NewStatus(framework.Success, "")
+	}
+	p.recordPodEvent(pod, d.pp, corev1.EventTypeNormal, ReasonPlacementPolicyApplied,
+		"%s placement policy %s applied, reserved on node %s (matches the node selector: %t)",
+		d.pp.Spec.EnforcementMode, d.pp.Name, nodeName, nodeMatchesLabels)
+	return framework.NewStatus(framework.Success, "")
+}
+
+// Unreserve rolls back the placement recorded in Reserve when the pod is rejected
+// by a later plugin or fails binding.
+func (p *Plugin) Unreserve(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, nodeName string) {
+	p.assumedPlacements.Forget(pod.UID)
+}
 
-	// Transform the highest to lowest score range to fit the framework's min to max node score range.
-	oldRange := highest - lowest
-	newRange := framework.MaxNodeScore - framework.MinNodeScore
-	for i, nodeScore := range scores {
-		if oldRange == 0 {
-			scores[i].Score = framework.MinNodeScore
-		} else {
-			scores[i].Score = ((nodeScore.Score - lowest) * newRange / oldRange) + framework.MinNodeScore
+// getPlacementPolicyForPod returns the placement policy applied to the pod, and the names of the
+// other placement policies matching the pod with the same weight
+func (p *Plugin) getPlacementPolicyForPod(ctx context.Context, pod *corev1.Pod) (*v1alpha1.PlacementPolicy, []string, error) {
+	start := time.Now()
+	ppList, err := p.ppMgr.GetPlacementPoliciesForPod(ctx, pod)
+	metrics.LookupLatency.Observe(time.Since(start).Seconds())
+	if err != nil || len(ppList) == 0 {
+		return nil, nil, err
+	}
+	var conflictingPolicies []string
+	for _, pp := range ppList[1:] {
+		if pp.Spec.Weight == ppList[0].Spec.Weight {
+			conflictingPolicies = append(conflictingPolicies, pp.Name)
 		}
 	}
-
-	klog.InfoS("normalized scores", "pod", pod.Name, "scores", scores)
-	return framework.NewStatus(framework.Success, "")
+	return ppList[0], conflictingPolicies, nil
 }
 
 func (p *Plugin) getPreFilterStateKey() framework.StateKey {
@@ -333,27 +625,77 @@ func (p *Plugin) getPreScoreStateKey() framework.StateKey {
 	return framework.StateKey(fmt.Sprintf("Prescore-%v", p.Name()))
 }
 
-// checkHasLabels checks if the labels exist in the provided set
-func checkHasLabels(l, wantLabels map[string]string) bool {
-	if len(l) < len(wantLabels) {
-		return false
+// readPreFilterStateData returns the state data written by PreFilter, or nil if
+// there is no Strict placement policy for the pod.
+func (p *Plugin) readPreFilterStateData(state *framework.CycleState) (*stateData, error) {
+	data, err := state.Read(p.getPreFilterStateKey())
+	if err != nil {
+		if err == framework.ErrNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read state: %v", err)
 	}
+	d, ok := data.(*stateData)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast state data")
+	}
+	return d, nil
+}
 
-	for k, v := range wantLabels {
-		if l[k] != v {
-			return false
+// readStateData returns the state data written by PreFilter for Strict placement
+// policies or by PreScore for BestEffort ones. It returns nil if there is no
+// placement policy for the pod.
+func (p *Plugin) readStateData(state *framework.CycleState) (*stateData, error) {
+	for _, key := range []framework.StateKey{p.getPreFilterStateKey(), p.getPreScoreStateKey()} {
+		data, err := state.Read(key)
+		if err != nil {
+			if err == framework.ErrNotFound {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read state: %v", err)
+		}
+		d, ok := data.(*stateData)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast state data")
 		}
+		return d, nil
+	}
+	return nil, nil
+}
+
+// getNodePreference returns whether the pod should be placed on a node with labels matching the
+// node selector, and the score of the nodes of the preferred group. The score is proportional to the
+// number of pods, out of totalPods, the nodes with matching labels are away from the target size.
+func getNodePreference(podsOnNodeWithMatchingLabels, targetSize, totalPods int, scoreMagnitude int64) (bool, int64) {
+	// if the number of pods on the node with matching labels is less than the target size, then we should prefer the node
+	if podsOnNodeWithMatchingLabels < targetSize {
+		return true, scaleNodeScore(targetSize-podsOnNodeWithMatchingLabels, totalPods, scoreMagnitude)
 	}
-	return true
+	// otherwise placing the pod on a node with matching labels would exceed the target size by one more pod
+	return false, scaleNodeScore(podsOnNodeWithMatchingLabels-targetSize+1, totalPods, scoreMagnitude)
 }
 
-// groupNodesWithLabels groups all nodes that match the node labels defined in the placement policy
-func groupNodesWithLabels(nodeList []*corev1.Node, labels map[string]string) map[string]*corev1.Node {
-	// nodeWithMatchingLabels is a group of nodes that have the same labels as defined in the placement policy
+// scaleNodeScore scales the deviation from the target size to the range between the framework's
+// min node score and the score magnitude
+func scaleNodeScore(deviation, totalPods int, scoreMagnitude int64) int64 {
+	if totalPods < deviation {
+		totalPods = deviation
+	}
+	score := scoreMagnitude * int64(deviation) / int64(totalPods)
+	// a deviation of at least one pod always results in a preference
+	if score < framework.MinNodeScore+1 {
+		score = framework.MinNodeScore + 1
+	}
+	return score
+}
+
+// groupNodesWithLabels groups all nodes that match the node selector defined in the placement policy
+func groupNodesWithLabels(nodeList []*corev1.Node, selector labels.Selector) map[string]*corev1.Node {
+	// nodeWithMatchingLabels is a group of nodes that have labels matching the node selector defined in the placement policy
 	nodeWithMatchingLabels := make(map[string]*corev1.Node)
 
 	for _, node := range nodeList {
-		if checkHasLabels(node.Labels, labels) {
+		if selector.Matches(labels.Set(node.Labels)) {
 			nodeWithMatchingLabels[node.Name] = node
 			continue
 		}
@@ -362,12 +704,15 @@ func groupNodesWithLabels(nodeList []*corev1.Node, labels map[string]string) map
 	return nodeWithMatchingLabels
 }
 
-// groupPodsBasedOnNodePreference groups all pods that match the node labels defined in the placement policy
-func groupPodsBasedOnNodePreference(podList []*corev1.Pod, pod *corev1.Pod, nodeWithMatchingLabels map[string]*corev1.Node) []*corev1.Pod {
+// groupPodsBasedOnNodePreference groups all pods that match the node labels defined in the placement policy,
+// and the pods placed on the other nodes
+func groupPodsBasedOnNodePreference(podList []*corev1.Pod, pod *corev1.Pod, nodeWithMatchingLabels map[string]*corev1.Node, assumedPlacements *core.AssumedPlacementCache) ([]*corev1.Pod, []*corev1.Pod) {
 	// podsOnNodeWithMatchingLabels is a group of pods with matching pod labels defined in placement policy
-	// that are already on the nodes with matching labels or annotated to be on the nodes with matching node labels
+	// that are already on the nodes with matching labels or assumed to be on the nodes with matching node labels
 	// by the placement policy scheduler plugin
 	podsOnNodeWithMatchingLabels := []*corev1.Pod{}
+	// podsOnOtherNodes is the group of the other pods already on a node or assumed to be on a node
+	podsOnOtherNodes := []*corev1.Pod{}
 
 	for _, p := range podList {
 		// this scheduling cycle is for the current pod on a node, we should skip it
@@ -377,30 +722,71 @@ func groupPodsBasedOnNodePreference(podList []*corev1.Pod, pod *corev1.Pod, node
 		if p.Spec.NodeName != "" {
 			if _, ok := nodeWithMatchingLabels[p.Spec.NodeName]; ok {
 				podsOnNodeWithMatchingLabels = append(podsOnNodeWithMatchingLabels, p)
+				continue
 			}
+			podsOnOtherNodes = append(podsOnOtherNodes, p)
 			continue
 		}
 		// we could be at this point because of the following reasons:
 		// 1. pod has not yet gone through scheduling process
-		//    - in this case, the nodename and custom annotation set by our plugin is empty
-		// 2. pod has gone through scheduling process but the nominated node hasn't been set yet
-		//    - in this case, the nodename could be empty and we'll rely on the annotation to
-		//		determine which group of nodes the pod is expected to land.
-		ann := p.Annotations[v1alpha1.PlacementPolicyPreferenceAnnotationKey]
-		// if the annotation is empty, we assume that the pod is still in the process of being scheduled
-		if ann == "" {
+		//    - in this case, the nodename is empty and there is no assumed placement for the pod
+		// 2. pod has been reserved on a node but the binding hasn't completed yet
+		//    - in this case, the nodename is empty and we'll rely on the assumed placement to
+		//		determine which group of nodes the pod is going to land on.
+		placement, ok := assumedPlacements.Get(p.UID)
+		if !ok {
 			continue
 		}
-		preferredNodeWithMatchingLabels, err := strconv.ParseBool(ann)
-		if err != nil {
-			continue
-		}
-		// if the annotation is set to true, we count the pod as a pod on a node with matching labels
-		if preferredNodeWithMatchingLabels {
+		// if the pod is reserved on a node with matching labels, we count it as a pod on a node with matching labels
+		if placement.NodeWithMatchingLabels {
 			podsOnNodeWithMatchingLabels = append(podsOnNodeWithMatchingLabels, p)
 			continue
 		}
+		podsOnOtherNodes = append(podsOnOtherNodes, p)
 	}
 
-	return podsOnNodeWithMatchingLabels
+	return podsOnNodeWithMatchingLabels, podsOnOtherNodes
+}
+
+// getPodNodeName returns the node the pod is bound to or, if it's not bound yet, the node it
+// was reserved on by the plugin. It returns an empty string if the pod is not scheduled yet.
+func getPodNodeName(pod *corev1.Pod, assumedPlacements *core.AssumedPlacementCache) string {
+	if pod.Spec.NodeName != "" {
+		return pod.Spec.NodeName
+	}
+	if placement, ok := assumedPlacements.Get(pod.UID); ok {
+		return placement.NodeName
+	}
+	return ""
+}
+
+// annotatePod asynchronously annotates the pod with the placement policy and the
+// node preference for visibility, if enabled.
+func (p *Plugin) annotatePod(pod *corev1.Pod, pp *v1alpha1.PlacementPolicy, preferredNodeWithMatchingLabels bool) {
+	if !p.annotatePods {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), annotatePodTimeout)
+		defer cancel()
+		if err := p.ppMgr.AnnotatePod(ctx, pod, pp, preferredNodeWithMatchingLabels); err != nil {
+			klog.ErrorS(err, "failed to annotate pod", "pod", klog.KObj(pod))
+			metrics.AnnotationFailures.WithLabelValues(pp.Namespace, pp.Name).Inc()
+		}
+	}()
+}
+
+// forgetPod removes the assumed placement of a deleted pod.
+func (p *Plugin) forgetPod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		if pod, ok = tombstone.Obj.(*corev1.Pod); !ok {
+			return
+		}
+	}
+	p.assumedPlacements.Forget(pod.UID)
 }
@@ -1,27 +1,36 @@
 package placementpolicy
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/metrics"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/component-base/metrics/testutil"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
 )
 
 func TestGroupNodesWithLabels(t *testing.T) {
 	tests := []struct {
 		name     string
 		nodeList []*corev1.Node
-		labels   map[string]string
+		selector *metav1.LabelSelector
 		want     func() map[string]*framework.NodeInfo
 	}{
 		{
 			name:     "no nodes",
 			nodeList: []*corev1.Node{},
-			labels:   map[string]string{"foo": "bar"},
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
 			want:     func() map[string]*framework.NodeInfo { return map[string]*framework.NodeInfo{} },
 		},
 		{
@@ -30,8 +39,8 @@ func TestGroupNodesWithLabels(t *testing.T) {
 				{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
 				{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
 			},
-			labels: map[string]string{"foo": "bar"},
-			want:   func() map[string]*framework.NodeInfo { return map[string]*framework.NodeInfo{} },
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			want:     func() map[string]*framework.NodeInfo { return map[string]*framework.NodeInfo{} },
 		},
 		{
 			name: "matching nodes found",
@@ -40,7 +49,7 @@ func TestGroupNodesWithLabels(t *testing.T) {
 				{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
 				{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"foo": "bar", "baz": "qux"}}},
 			},
-			labels: map[string]string{"foo": "bar"},
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
 			want: func() map[string]*framework.NodeInfo {
 				n1 := framework.NewNodeInfo()
 				n1.SetNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"foo": "bar"}}})
@@ -48,6 +57,31 @@ func TestGroupNodesWithLabels(t *testing.T) {
 				n3 := framework.NewNodeInfo()
 				n3.SetNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"foo": "bar", "baz": "qux"}}})
 
+				return map[string]*framework.NodeInfo{
+					"node1": n1,
+					"node3": n3,
+				}
+			},
+		},
+		{
+			name: "matching nodes found with match expressions",
+			nodeList: []*corev1.Node{
+				{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"kubernetes.azure.com/scalesetpriority": "spot"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"kubernetes.azure.com/scalesetpriority": "regular"}}},
+			},
+			selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "kubernetes.azure.com/scalesetpriority", Operator: metav1.LabelSelectorOpExists},
+				},
+			},
+			want: func() map[string]*framework.NodeInfo {
+				n1 := framework.NewNodeInfo()
+				n1.SetNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"kubernetes.azure.com/scalesetpriority": "spot"}}})
+
+				n3 := framework.NewNodeInfo()
+				n3.SetNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"kubernetes.azure.com/scalesetpriority": "regular"}}})
+
 				return map[string]*framework.NodeInfo{
 					"node1": n1,
 					"node3": n3,
@@ -58,13 +92,17 @@ func TestGroupNodesWithLabels(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := groupNodesWithLabels(tt.nodeList, tt.labels)
+			selector, err := metav1.LabelSelectorAsSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("LabelSelectorAsSelector(%v) error = %v", tt.selector, err)
+			}
+			got := groupNodesWithLabels(tt.nodeList, selector)
 			if len(got) != len(tt.want()) {
-				t.Errorf("groupNodesWithLabels(%v, %v) = %v, want %v", tt.nodeList, tt.labels, got, tt.want())
+				t.Errorf("groupNodesWithLabels(%v, %v) = %v, want %v", tt.nodeList, selector, got, tt.want())
 			}
 			for k := range tt.want() {
 				if _, ok := got[k]; !ok {
-					t.Errorf("groupNodesWithLabels(%v, %v) = %v, want %v", tt.nodeList, tt.labels, got, tt.want())
+					t.Errorf("groupNodesWithLabels(%v, %v) = %v, want %v", tt.nodeList, selector, got, tt.want())
 				}
 			}
 		})
@@ -77,6 +115,7 @@ func TestGroupPodsBasedOnNodePreference(t *testing.T) {
 		podList                []*corev1.Pod
 		pod                    *corev1.Pod
 		nodeWithMatchingLabels map[string]*corev1.Node
+		assumedPlacements      map[types.UID]core.AssumedPlacement
 		want                   []*corev1.Pod
 	}{
 		{
@@ -122,24 +161,42 @@ func TestGroupPodsBasedOnNodePreference(t *testing.T) {
 			want: []*corev1.Pod{},
 		},
 		{
-			name: "no node name but annotation exists",
+			name: "no node name but assumed placement exists",
 			podList: []*corev1.Pod{
 				{ObjectMeta: metav1.ObjectMeta{Name: "pod1", UID: types.UID("pod1")}},
-				{ObjectMeta: metav1.ObjectMeta{Name: "pod2", UID: types.UID("pod2"), Annotations: map[string]string{v1alpha1.PlacementPolicyPreferenceAnnotationKey: "true"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod2", UID: types.UID("pod2")}},
 			},
 			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", UID: types.UID("pod1")}},
 			nodeWithMatchingLabels: map[string]*corev1.Node{
 				"node1": {ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"foo": "bar"}}},
 			},
+			assumedPlacements: map[types.UID]core.AssumedPlacement{
+				"pod2": {PolicyName: "pp", NodeName: "node1", NodeWithMatchingLabels: true},
+			},
 			want: []*corev1.Pod{
-				{ObjectMeta: metav1.ObjectMeta{Name: "pod2", UID: types.UID("pod2"), Annotations: map[string]string{v1alpha1.PlacementPolicyPreferenceAnnotationKey: "true"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod2", UID: types.UID("pod2")}},
 			},
 		},
 		{
-			name: "annotation exists but no matching node",
+			name: "assumed placement exists but no matching node",
 			podList: []*corev1.Pod{
 				{ObjectMeta: metav1.ObjectMeta{Name: "pod1", UID: types.UID("pod1")}},
-				{ObjectMeta: metav1.ObjectMeta{Name: "pod2", UID: types.UID("pod2"), Annotations: map[string]string{v1alpha1.PlacementPolicyPreferenceAnnotationKey: "false"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod2", UID: types.UID("pod2")}},
+			},
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", UID: types.UID("pod1")}},
+			nodeWithMatchingLabels: map[string]*corev1.Node{
+				"node1": {ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"foo": "bar"}}},
+			},
+			assumedPlacements: map[types.UID]core.AssumedPlacement{
+				"pod2": {PolicyName: "pp", NodeName: "node2", NodeWithMatchingLabels: false},
+			},
+			want: []*corev1.Pod{},
+		},
+		{
+			name: "annotation without assumed placement is ignored",
+			podList: []*corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod1", UID: types.UID("pod1")}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod2", UID: types.UID("pod2"), Annotations: map[string]string{v1alpha1.PlacementPolicyPreferenceAnnotationKey: "true"}}},
 			},
 			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", UID: types.UID("pod1")}},
 			nodeWithMatchingLabels: map[string]*corev1.Node{
@@ -151,7 +208,11 @@ func TestGroupPodsBasedOnNodePreference(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := groupPodsBasedOnNodePreference(tt.podList, tt.pod, tt.nodeWithMatchingLabels)
+			assumedPlacements := core.NewAssumedPlacementCache()
+			for uid, placement := range tt.assumedPlacements {
+				assumedPlacements.Assume(uid, placement)
+			}
+			got, _ := groupPodsBasedOnNodePreference(tt.podList, tt.pod, tt.nodeWithMatchingLabels, assumedPlacements)
 			if len(got) != len(tt.want) {
 				t.Errorf("groupPodsBasedOnNodePreference(%v, %v, %v) = %v, want %v", tt.podList, tt.pod, tt.nodeWithMatchingLabels, got, tt.want)
 			}
@@ -161,3 +222,558 @@ func TestGroupPodsBasedOnNodePreference(t *testing.T) {
 		})
 	}
 }
+
+func TestReserveUnreserve(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"node": "want"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"node": "unwant"}}},
+	}
+	fh, err := frameworkruntime.NewFramework(nil, nil, frameworkruntime.WithSnapshotSharedLister(newFakeSharedLister(nodes)))
+	if err != nil {
+		t.Fatalf("NewFramework() error = %v", err)
+	}
+	nodeSelector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}})
+	if err != nil {
+		t.Fatalf("LabelSelectorAsSelector() error = %v", err)
+	}
+	pp := &v1alpha1.PlacementPolicy{ObjectMeta: metav1.ObjectMeta{Name: "pp"}}
+
+	tests := []struct {
+		name      string
+		stateKey  func(p *Plugin) framework.StateKey
+		nodeName  string
+		want      core.AssumedPlacement
+		wantFound bool
+	}{
+		{
+			name:      "no placement policy for pod",
+			nodeName:  "node1",
+			wantFound: false,
+		},
+		{
+			name:      "strict policy reserved on node with matching labels",
+			stateKey:  (*Plugin).getPreFilterStateKey,
+			nodeName:  "node1",
+			want:      core.AssumedPlacement{PolicyName: "pp", NodeName: "node1", NodeWithMatchingLabels: true},
+			wantFound: true,
+		},
+		{
+			name:      "best effort policy reserved on node without matching labels",
+			stateKey:  (*Plugin).getPreScoreStateKey,
+			nodeName:  "node2",
+			want:      core.AssumedPlacement{PolicyName: "pp", NodeName: "node2", NodeWithMatchingLabels: false},
+			wantFound: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{
+				frameworkHandler:  fh,
+				assumedPlacements: core.NewAssumedPlacementCache(),
+			}
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", UID: types.UID("pod1")}}
+			state := framework.NewCycleState()
+			if tt.stateKey != nil {
+				state.Write(tt.stateKey(p), NewStateData(pod.Name, pp, nodeSelector, true, framework.MaxNodeScore, nil, nil))
+			}
+
+			if status := p.Reserve(context.Background(), state, pod, tt.nodeName); !status.IsSuccess() {
+				t.Fatalf("Reserve() status = %v", status)
+			}
+			got, found := p.assumedPlacements.Get(pod.UID)
+			if found != tt.wantFound {
+				t.Fatalf("Reserve() assumed placement found = %v, want %v", found, tt.wantFound)
+			}
+			if got != tt.want {
+				t.Errorf("Reserve() assumed placement = %+v, want %+v", got, tt.want)
+			}
+
+			p.Unreserve(context.Background(), state, pod, tt.nodeName)
+			if _, found := p.assumedPlacements.Get(pod.UID); found {
+				t.Errorf("Unreserve() assumed placement was not removed")
+			}
+		})
+	}
+}
+
+func TestComputeCountedStateData(t *testing.T) {
+	metrics.Register()
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"node": "want"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"node": "unwant"}}},
+	}
+	fh, err := frameworkruntime.NewFramework(nil, nil, frameworkruntime.WithSnapshotSharedLister(newFakeSharedLister(nodes)))
+	if err != nil {
+		t.Fatalf("NewFramework() error = %v", err)
+	}
+	makePod := func(name, app, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name), Labels: map[string]string{"app": app}},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+		}
+	}
+	pod := makePod("pod", "nginx", "")
+	policyPods := []*corev1.Pod{
+		pod,
+		makePod("bound-want", "nginx", "node1"),
+		makePod("bound-unwant", "nginx", "node2"),
+		makePod("assumed-want", "nginx", ""),
+		makePod("pending", "nginx", ""),
+	}
+	other := makePod("other", "redis", "node1")
+	targetSize := intstr.FromString("50%")
+	pp := &v1alpha1.PlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "default"},
+		Spec: v1alpha1.PlacementPolicySpec{
+			PodSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+			Policy: &v1alpha1.Policy{
+				Action:     v1alpha1.ActionMust,
+				TargetSize: &targetSize,
+				Rounding:   v1alpha1.RoundingModeDown,
+			},
+		},
+	}
+
+	p := &Plugin{
+		frameworkHandler:  fh,
+		ppMgr:             &fakeManager{pp: pp, pods: policyPods},
+		assumedPlacements: core.NewAssumedPlacementCache(),
+		scoreMagnitude:    framework.MaxNodeScore,
+		counters:          core.NewPlacementCounters(),
+	}
+	p.assumedPlacements.Assume("assumed-want", core.AssumedPlacement{PolicyName: "pp", NodeName: "node1", NodeWithMatchingLabels: true})
+	for _, node := range nodes {
+		p.counters.NodeEventHandler().OnAdd(node)
+	}
+	for _, pod := range append(policyPods, other) {
+		p.counters.PodEventHandler().OnAdd(pod)
+	}
+
+	// the counters give the same state as the full recount of the pods
+	counted, _, err := p.computeCountedStateData(pod, pp, nil)
+	if err != nil {
+		t.Fatalf("computeCountedStateData() error = %v", err)
+	}
+	p.counters = nil
+	data, err := p.computeStateData(context.Background(), pod, pp, nil, nodes)
+	if err != nil {
+		t.Fatalf("computeStateData() error = %v", err)
+	}
+	recounted := data.(*stateData)
+	if counted.preferredNodeWithMatchingLabels != recounted.preferredNodeWithMatchingLabels || counted.preferenceScore != recounted.preferenceScore {
+		t.Errorf("computeCountedStateData() preference = (%v, %d), want (%v, %d)", counted.preferredNodeWithMatchingLabels, counted.preferenceScore,
+			recounted.preferredNodeWithMatchingLabels, recounted.preferenceScore)
+	}
+	if counted.counts.totalPods != recounted.counts.totalPods || !reflect.DeepEqual(counted.counts.podsInGroup, recounted.counts.podsInGroup) {
+		t.Errorf("computeCountedStateData() counts = %d %v, want %d %v", counted.counts.totalPods, counted.counts.podsInGroup,
+			recounted.counts.totalPods, recounted.counts.podsInGroup)
+	}
+	for _, other := range append(policyPods, other) {
+		gotPlaced, gotCounted := counted.counts.placed(other.UID)
+		wantPlaced, wantCounted := recounted.counts.placed(other.UID)
+		if gotPlaced != wantPlaced || gotCounted != wantCounted {
+			t.Errorf("computeCountedStateData() pod %s placed = (%v, %v), want (%v, %v)", other.Name, gotPlaced, gotCounted, wantPlaced, wantCounted)
+		}
+	}
+
+	// a mismatch with the full recount is reported
+	metrics.CounterMismatches.Reset()
+	p.counters = core.NewPlacementCounters()
+	p.checkCounters(pod, counted, recounted)
+	recounted.counts.podsInGroup[0]++
+	p.checkCounters(pod, counted, recounted)
+	got, err := testutil.GetCounterMetricValue(metrics.CounterMismatches.WithLabelValues(pp.Namespace, pp.Name))
+	if err != nil {
+		t.Fatalf("GetCounterMetricValue() error = %v", err)
+	}
+	if got != 1 {
+		t.Errorf("counter mismatches = %v, want 1", got)
+	}
+}
+
+func TestPreScore(t *testing.T) {
+	metrics.Register()
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"node": "want"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"node": "unwant"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"node": "want"}}},
+	}
+	fh, err := frameworkruntime.NewFramework(nil, nil, frameworkruntime.WithSnapshotSharedLister(newFakeSharedLister(nodes)))
+	if err != nil {
+		t.Fatalf("NewFramework() error = %v", err)
+	}
+	makePod := func(name, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name), Labels: map[string]string{"app": "nginx"}},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+		}
+	}
+	pod := makePod("pod", "")
+	targetSize := intstr.FromString("50%")
+	pp := &v1alpha1.PlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "default"},
+		Spec: v1alpha1.PlacementPolicySpec{
+			EnforcementMode: v1alpha1.EnforcementModeBestEffort,
+			PodSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+			NodeSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+			Policy: &v1alpha1.Policy{
+				Action:     v1alpha1.ActionMust,
+				TargetSize: &targetSize,
+				Rounding:   v1alpha1.RoundingModeDown,
+			},
+		},
+	}
+	p := &Plugin{
+		frameworkHandler: fh,
+		ppMgr: &fakeManager{pp: pp, pods: []*corev1.Pod{
+			pod,
+			makePod("on-node1", "node1"),
+			makePod("on-node2", "node2"),
+			makePod("on-node3", "node3"),
+		}},
+		assumedPlacements: core.NewAssumedPlacementCache(),
+		scoreMagnitude:    framework.MaxNodeScore,
+	}
+
+	// node3 is filtered for the pod, but the pod on it is still counted on the nodes with matching labels
+	state := framework.NewCycleState()
+	if status := p.PreScore(context.Background(), state, pod, nodes[:2]); !status.IsSuccess() {
+		t.Fatalf("PreScore() status = %v", status)
+	}
+	data, err := state.Read(p.getPreScoreStateKey())
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	d := data.(*stateData)
+	if got, want := d.counts.podsInGroup, []int{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PreScore() pods in group = %v, want %v", got, want)
+	}
+	if d.preferredNodeWithMatchingLabels {
+		t.Errorf("PreScore() preferredNodeWithMatchingLabels = true, want false")
+	}
+}
+
+func TestGetNodePreference(t *testing.T) {
+	tests := []struct {
+		name                         string
+		podsOnNodeWithMatchingLabels int
+		targetSize                   int
+		totalPods                    int
+		wantPreferred                bool
+		wantScore                    int64
+	}{
+		{
+			name:                         "no pods on nodes with matching labels",
+			podsOnNodeWithMatchingLabels: 0,
+			targetSize:                   4,
+			totalPods:                    10,
+			wantPreferred:                true,
+			wantScore:                    40,
+		},
+		{
+			name:                         "one pod short of the target size",
+			podsOnNodeWithMatchingLabels: 3,
+			targetSize:                   4,
+			totalPods:                    10,
+			wantPreferred:                true,
+			wantScore:                    10,
+		},
+		{
+			name:                         "target size reached",
+			podsOnNodeWithMatchingLabels: 4,
+			targetSize:                   4,
+			totalPods:                    10,
+			wantPreferred:                false,
+			wantScore:                    10,
+		},
+		{
+			name:                         "target size exceeded",
+			podsOnNodeWithMatchingLabels: 6,
+			targetSize:                   0,
+			totalPods:                    10,
+			wantPreferred:                false,
+			wantScore:                    70,
+		},
+		{
+			name:                         "small deviation out of many pods",
+			podsOnNodeWithMatchingLabels: 99,
+			targetSize:                   100,
+			totalPods:                    1000,
+			wantPreferred:                true,
+			wantScore:                    1,
+		},
+		{
+			name:                         "no pods",
+			podsOnNodeWithMatchingLabels: 0,
+			targetSize:                   0,
+			totalPods:                    0,
+			wantPreferred:                false,
+			wantScore:                    framework.MaxNodeScore,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPreferred, gotScore := getNodePreference(tt.podsOnNodeWithMatchingLabels, tt.targetSize, tt.totalPods, framework.MaxNodeScore)
+			if gotPreferred != tt.wantPreferred || gotScore != tt.wantScore {
+				t.Errorf("getNodePreference() = (%v, %d), want (%v, %d)", gotPreferred, gotScore, tt.wantPreferred, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"node": "want"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"node": "unwant"}}},
+	}
+	fh, err := frameworkruntime.NewFramework(nil, nil, frameworkruntime.WithSnapshotSharedLister(newFakeSharedLister(nodes)))
+	if err != nil {
+		t.Fatalf("NewFramework() error = %v", err)
+	}
+	nodeSelector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}})
+	if err != nil {
+		t.Fatalf("LabelSelectorAsSelector() error = %v", err)
+	}
+	pp := &v1alpha1.PlacementPolicy{ObjectMeta: metav1.ObjectMeta{Name: "pp"}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", UID: types.UID("pod1")}}
+
+	tests := []struct {
+		name      string
+		preferred bool
+		nodeName  string
+		want      int64
+	}{
+		{
+			name:      "node with matching labels preferred",
+			preferred: true,
+			nodeName:  "node1",
+			want:      30,
+		},
+		{
+			name:      "node without matching labels not preferred",
+			preferred: true,
+			nodeName:  "node2",
+			want:      framework.MinNodeScore,
+		},
+		{
+			name:      "node without matching labels preferred",
+			preferred: false,
+			nodeName:  "node2",
+			want:      30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{frameworkHandler: fh}
+			state := framework.NewCycleState()
+			state.Write(p.getPreScoreStateKey(), NewStateData(pod.Name, pp, nodeSelector, tt.preferred, 30, nil, nil))
+
+			got, status := p.Score(context.Background(), state, pod, tt.nodeName)
+			if !status.IsSuccess() {
+				t.Fatalf("Score() status = %v", status)
+			}
+			if got != tt.want {
+				t.Errorf("Score() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	metrics.Register()
+	nodeSelector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}})
+	if err != nil {
+		t.Fatalf("LabelSelectorAsSelector() error = %v", err)
+	}
+	pp := &v1alpha1.PlacementPolicy{ObjectMeta: metav1.ObjectMeta{Name: "pp-filter", Namespace: "default"}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: types.UID("pod1")}}
+
+	tests := []struct {
+		name           string
+		preferred      bool
+		node           *corev1.Node
+		want           framework.Code
+		wantRejections float64
+	}{
+		{
+			name:      "node with matching labels preferred",
+			preferred: true,
+			node:      &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"node": "want"}}},
+			want:      framework.Success,
+		},
+		{
+			name:           "node without matching labels not preferred",
+			preferred:      true,
+			node:           &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"node": "unwant"}}},
+			want:           framework.Unschedulable,
+			wantRejections: 1,
+		},
+		{
+			name:           "node with matching labels not preferred",
+			preferred:      false,
+			node:           &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"node": "want"}}},
+			want:           framework.Unschedulable,
+			wantRejections: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.FilterRejections.Reset()
+			p := &Plugin{}
+			state := framework.NewCycleState()
+			state.Write(p.getPreFilterStateKey(), NewStateData(pod.Name, pp, nodeSelector, tt.preferred, framework.MaxNodeScore, nil, nil))
+			nodeInfo := framework.NewNodeInfo()
+			nodeInfo.SetNode(tt.node)
+
+			if status := p.Filter(context.Background(), state, pod, nodeInfo); status.Code() != tt.want {
+				t.Errorf("Filter() status = %v, want %v", status.Code(), tt.want)
+			}
+			got, err := testutil.GetCounterMetricValue(metrics.FilterRejections.WithLabelValues(pp.Namespace, pp.Name))
+			if err != nil {
+				t.Fatalf("GetCounterMetricValue() error = %v", err)
+			}
+			if got != tt.wantRejections {
+				t.Errorf("filter rejections = %v, want %v", got, tt.wantRejections)
+			}
+		})
+	}
+}
+
+func TestEventsToRegister(t *testing.T) {
+	p := &Plugin{}
+	got := make(map[framework.GVK]framework.ActionType)
+	for _, event := range p.EventsToRegister() {
+		got[event.Resource] |= event.ActionType
+	}
+	want := map[framework.GVK]framework.ActionType{
+		framework.Node: framework.Add | framework.UpdateNodeLabel,
+		framework.Pod:  framework.Delete,
+		"placementpolicies.v1alpha1.placement-policy.scheduling.x-k8s.io":        framework.All,
+		"clusterplacementpolicies.v1alpha1.placement-policy.scheduling.x-k8s.io": framework.All,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EventsToRegister() = %v, want %v", got, want)
+	}
+}
+
+func TestGetArgs(t *testing.T) {
+	tests := []struct {
+		name                       string
+		obj                        runtime.Object
+		wantDefaultEnforcementMode v1alpha1.EnforcementMode
+		wantResyncPeriod           time.Duration
+		wantScoreMagnitude         int64
+		wantAnnotatePods           bool
+		wantEnablePreemption       bool
+		wantErr                    bool
+	}{
+		{
+			name:                       "no args",
+			wantDefaultEnforcementMode: v1alpha1.EnforcementModeBestEffort,
+			wantScoreMagnitude:         framework.MaxNodeScore,
+			wantAnnotatePods:           true,
+		},
+		{
+			name: "args from the plugin config",
+			obj: &runtime.Unknown{
+				Raw:         []byte(`{"defaultEnforcementMode":"Strict","resyncPeriod":"5m","scoreMagnitude":50,"annotatePods":false,"enablePreemption":true}`),
+				ContentType: runtime.ContentTypeJSON,
+			},
+			wantDefaultEnforcementMode: v1alpha1.EnforcementModeStrict,
+			wantResyncPeriod:           5 * time.Minute,
+			wantScoreMagnitude:         50,
+			wantAnnotatePods:           false,
+			wantEnablePreemption:       true,
+		},
+		{
+			name: "partial args are defaulted",
+			obj: &runtime.Unknown{
+				Raw:         []byte(`{"scoreMagnitude":10}`),
+				ContentType: runtime.ContentTypeJSON,
+			},
+			wantDefaultEnforcementMode: v1alpha1.EnforcementModeBestEffort,
+			wantScoreMagnitude:         10,
+			wantAnnotatePods:           true,
+		},
+		{
+			name: "invalid args",
+			obj: &runtime.Unknown{
+				Raw:         []byte(`{"scoreMagnitude":1000}`),
+				ContentType: runtime.ContentTypeJSON,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getArgs(tt.obj)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got.DefaultEnforcementMode != tt.wantDefaultEnforcementMode {
+				t.Errorf("getArgs() defaultEnforcementMode = %s, want %s", *got.DefaultEnforcementMode, tt.wantDefaultEnforcementMode)
+			}
+			if got.ResyncPeriod.Duration != tt.wantResyncPeriod {
+				t.Errorf("getArgs() resyncPeriod = %v, want %v", got.ResyncPeriod.Duration, tt.wantResyncPeriod)
+			}
+			if *got.ScoreMagnitude != tt.wantScoreMagnitude {
+				t.Errorf("getArgs() scoreMagnitude = %d, want %d", *got.ScoreMagnitude, tt.wantScoreMagnitude)
+			}
+			if *got.AnnotatePods != tt.wantAnnotatePods {
+				t.Errorf("getArgs() annotatePods = %v, want %v", *got.AnnotatePods, tt.wantAnnotatePods)
+			}
+			if *got.EnablePreemption != tt.wantEnablePreemption {
+				t.Errorf("getArgs() enablePreemption = %v, want %v", *got.EnablePreemption, tt.wantEnablePreemption)
+			}
+		})
+	}
+}
+
+// fakeSharedLister is a framework.SharedLister backed by a static list of nodes
+type fakeSharedLister struct {
+	nodeInfos []*framework.NodeInfo
+}
+
+func newFakeSharedLister(nodes []*corev1.Node) *fakeSharedLister {
+	l := &fakeSharedLister{}
+	for _, node := range nodes {
+		nodeInfo := framework.NewNodeInfo()
+		nodeInfo.SetNode(node)
+		l.nodeInfos = append(l.nodeInfos, nodeInfo)
+	}
+	return l
+}
+
+func (l *fakeSharedLister) NodeInfos() framework.NodeInfoLister {
+	return l
+}
+
+func (l *fakeSharedLister) List() ([]*framework.NodeInfo, error) {
+	return l.nodeInfos, nil
+}
+
+func (l *fakeSharedLister) HavePodsWithAffinityList() ([]*framework.NodeInfo, error) {
+	return nil, nil
+}
+
+func (l *fakeSharedLister) HavePodsWithRequiredAntiAffinityList() ([]*framework.NodeInfo, error) {
+	return nil, nil
+}
+
+func (l *fakeSharedLister) Get(nodeName string) (*framework.NodeInfo, error) {
+	for _, nodeInfo := range l.nodeInfos {
+		if nodeInfo.Node().Name == nodeName {
+			return nodeInfo, nil
+		}
+	}
+	return nil, fmt.Errorf("nodeinfo not found for node name %q", nodeName)
+}
@@ -0,0 +1,299 @@
+package placementpolicy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultpreemption"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// preemptionCandidate is a node of the preferred group the pod fits on once the victims are removed
+type preemptionCandidate struct {
+	nodeName string
+	victims  []*corev1.Pod
+	// preempted is the number of victims that are not pods of the placement policy placed on the
+	// wrong group of nodes, i.e. the pods of other workloads with a lower priority
+	preempted int
+	// pdbViolations is the number of victims whose pod disruption budget is violated
+	pdbViolations int
+}
+
+// lessDisruptive returns true if the candidate violates fewer pod disruption budgets than the other
+// candidate, or as many but preempts fewer pods of other workloads, or as many but with fewer
+// victims overall
+func (c *preemptionCandidate) lessDisruptive(other *preemptionCandidate) bool {
+	if c.pdbViolations != other.pdbViolations {
+		return c.pdbViolations < other.pdbViolations
+	}
+	if c.preempted != other.preempted {
+		return c.preempted < other.preempted
+	}
+	return len(c.victims) < len(other.victims)
+}
+
+// policyPreferences computes the node preference of the pods of the placement policy once per
+// scope, i.e. once for all the pods, or once per workload with the PerOwner scope, to tell the
+// pods placed on a group of nodes they don't prefer
+type policyPreferences struct {
+	p        *Plugin
+	pp       *v1alpha1.PlacementPolicy
+	nodeList []*corev1.Node
+	// states are the node preferences of the pods of each scope, counting all the pods
+	states map[types.UID]*stateData
+}
+
+// isMisplaced returns true if the pod of the placement policy running on the node would be placed on
+// another group of nodes if it was scheduled again
+func (pp *policyPreferences) isMisplaced(ctx context.Context, pod *corev1.Pod, node *corev1.Node) (bool, error) {
+	var scope types.UID
+	if pp.pp.Spec.Policy.Scope == v1alpha1.ScopePerOwner {
+		if owner := metav1.GetControllerOf(pod); owner != nil {
+			scope = owner.UID
+		}
+	}
+	d, ok := pp.states[scope]
+	if !ok {
+		// a pod without UID counts all the pods of its scope, as it's none of them
+		scopePod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace, OwnerReferences: pod.OwnerReferences}}
+		data, err := pp.p.computeStateData(ctx, scopePod, pp.pp, nil, pp.nodeList)
+		if err != nil {
+			return false, err
+		}
+		d = data.(*stateData)
+		pp.states[scope] = d
+	}
+	return d.misplaced(node)
+}
+
+// preempt finds the node of the preferred group the pod fits on once the victims are removed, deletes
+// the victims and returns the name of the node. The victims are the pods with a lower priority than the
+// pod, and the pods counted by the placement policy that are on a group of nodes they don't prefer with
+// a priority up to the priority of the pod, as they are placed on their preferred nodes when they are
+// recreated. The other pods counted by the placement policy are never preempted, as their replacements
+// would prefer the same nodes. As in the default preemption, the victims whose pod disruption budget
+// would be violated are preempted last, and the node with the fewest violations is selected. It
+// returns an empty name if preemption doesn't help the pod.
+func (p *Plugin) preempt(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, d *stateData, filteredNodeStatusMap framework.NodeToStatusMap) (string, error) {
+	nodeInfos := p.frameworkHandler.SnapshotSharedLister().NodeInfos()
+	if !defaultpreemption.PodEligibleToPreemptOthers(pod, nodeInfos, filteredNodeStatusMap[pod.Status.NominatedNodeName]) {
+		return "", nil
+	}
+	nodeInfoList, err := nodeInfos.List()
+	if err != nil {
+		return "", fmt.Errorf("failed to get nodes in the cluster: %v", err)
+	}
+	nodeList := make([]*corev1.Node, 0, len(nodeInfoList))
+	for _, nodeInfo := range nodeInfoList {
+		nodeList = append(nodeList, nodeInfo.Node())
+	}
+	podList, err := p.ppMgr.GetPodsForPlacementPolicy(ctx, d.pp)
+	if err != nil {
+		return "", fmt.Errorf("failed to get pods for placement policy: %v", err)
+	}
+	policyPods := make(map[types.UID]bool, len(podList))
+	for _, other := range podList {
+		policyPods[other.UID] = true
+	}
+	var pdbs []*policyv1.PodDisruptionBudget
+	if p.pdbLister != nil {
+		if pdbs, err = p.pdbLister.List(labels.Everything()); err != nil {
+			return "", fmt.Errorf("failed to list pod disruption budgets: %v", err)
+		}
+	}
+	preferences := &policyPreferences{p: p, pp: d.pp, nodeList: nodeList, states: make(map[types.UID]*stateData)}
+
+	var best *preemptionCandidate
+	for _, nodeInfo := range nodeInfoList {
+		node := nodeInfo.Node()
+		status, ok := filteredNodeStatusMap[node.Name]
+		// removing pods doesn't help on the nodes filtered by the placement policy, or filtered
+		// for reasons other than the pods running on them
+		if !ok || status.FailedPlugin() == p.Name() || status.Code() == framework.UnschedulableAndUnresolvable || !d.inPreferredGroup(node) {
+			continue
+		}
+		isVictim := func(victim *corev1.Pod) (bool, bool, error) {
+			if !policyPods[victim.UID] {
+				return corev1helpers.PodPriority(victim) < corev1helpers.PodPriority(pod), false, nil
+			}
+			if corev1helpers.PodPriority(victim) > corev1helpers.PodPriority(pod) {
+				return false, false, nil
+			}
+			misplaced, err := preferences.isMisplaced(ctx, victim, node)
+			return misplaced, misplaced, err
+		}
+		candidate, err := p.selectVictimsOnNode(ctx, state, pod, nodeInfo.Clone(), pdbs, isVictim)
+		if err != nil {
+			return "", err
+		}
+		if candidate != nil && (best == nil || candidate.lessDisruptive(best)) {
+			best = candidate
+		}
+	}
+	if best == nil {
+		return "", nil
+	}
+
+	for _, victim := range best.victims {
+		if waitingPod := p.frameworkHandler.GetWaitingPod(victim.UID); waitingPod != nil {
+			waitingPod.Reject(p.Name(), "preempted")
+		} else if err := util.DeletePod(p.frameworkHandler.ClientSet(), victim); err != nil {
+			return "", fmt.Errorf("failed to preempt pod %s/%s: %v", victim.Namespace, victim.Name, err)
+		}
+		if p.eventRecorder != nil {
+			p.eventRecorder.Eventf(victim, pod, corev1.EventTypeNormal, ReasonPlacementPolicyPreempted, eventActionScheduling,
+				"Preempted by %s/%s on node %s to enforce placement policy %s", pod.Namespace, pod.Name, best.nodeName, d.pp.Name)
+		}
+	}
+	metrics.PreemptionVictims.WithLabelValues(d.pp.Namespace, d.pp.Name).Add(float64(len(best.victims)))
+	p.recordPodAndPolicyEvents(pod, d.pp, corev1.EventTypeNormal, ReasonPlacementPolicyPreempted,
+		"Strict placement policy %s preempted %d pods on node %s for pod %s/%s",
+		d.pp.Name, len(best.victims), best.nodeName, pod.Namespace, pod.Name)
+
+	// the lower priority pods nominated on the node may no longer fit on it
+	var nominatedPods []*corev1.Pod
+	for _, podInfo := range p.frameworkHandler.NominatedPodsForNode(best.nodeName) {
+		if corev1helpers.PodPriority(podInfo.Pod) < corev1helpers.PodPriority(pod) {
+			nominatedPods = append(nominatedPods, podInfo.Pod)
+		}
+	}
+	if err := util.ClearNominatedNodeName(p.frameworkHandler.ClientSet(), nominatedPods...); err != nil {
+		klog.ErrorS(err, "failed to clear the nominated node of the lower priority pods", "node", best.nodeName)
+	}
+	return best.nodeName, nil
+}
+
+// selectVictimsOnNode returns the victims to remove from the node for the pod to fit on it, or nil if
+// the pod doesn't fit even when all the potential victims are removed. isVictim returns whether a pod
+// running on the node can be removed, and whether it's a pod of the placement policy placed on the
+// wrong group of nodes. As few victims as possible are removed: as in the default preemption, the
+// pods whose pod disruption budget would be violated are reprieved first, then the other pods. In
+// both cases, the pods of other workloads are reprieved first, the most important first, then the
+// misplaced pods of the placement policy.
+func (p *Plugin) selectVictimsOnNode(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, nodeInfo *framework.NodeInfo, pdbs []*policyv1.PodDisruptionBudget, isVictim func(*corev1.Pod) (bool, bool, error)) (*preemptionCandidate, error) {
+	state = state.Clone()
+	var potentialVictims []*framework.PodInfo
+	misplaced := make(map[types.UID]bool)
+	for _, podInfo := range nodeInfo.Pods {
+		victim, misplacedPod, err := isVictim(podInfo.Pod)
+		if err != nil {
+			return nil, err
+		}
+		if victim {
+			potentialVictims = append(potentialVictims, podInfo)
+			misplaced[podInfo.Pod.UID] = misplacedPod
+		}
+	}
+	if len(potentialVictims) == 0 {
+		return nil, nil
+	}
+
+	removePod := func(podInfo *framework.PodInfo) error {
+		if err := nodeInfo.RemovePod(podInfo.Pod); err != nil {
+			return err
+		}
+		return p.frameworkHandler.RunPreFilterExtensionRemovePod(ctx, state, pod, podInfo, nodeInfo).AsError()
+	}
+	for _, podInfo := range potentialVictims {
+		if err := removePod(podInfo); err != nil {
+			return nil, err
+		}
+	}
+	if !p.frameworkHandler.RunFilterPluginsWithNominatedPods(ctx, state, pod, nodeInfo).IsSuccess() {
+		return nil, nil
+	}
+
+	sort.SliceStable(potentialVictims, func(i, j int) bool {
+		if misplaced[potentialVictims[i].Pod.UID] != misplaced[potentialVictims[j].Pod.UID] {
+			return !misplaced[potentialVictims[i].Pod.UID]
+		}
+		return util.MoreImportantPod(potentialVictims[i].Pod, potentialVictims[j].Pod)
+	})
+	candidate := &preemptionCandidate{nodeName: nodeInfo.Node().Name}
+	reprieve := func(podInfo *framework.PodInfo) (bool, error) {
+		nodeInfo.AddPodInfo(podInfo)
+		if err := p.frameworkHandler.RunPreFilterExtensionAddPod(ctx, state, pod, podInfo, nodeInfo).AsError(); err != nil {
+			return false, err
+		}
+		if p.frameworkHandler.RunFilterPluginsWithNominatedPods(ctx, state, pod, nodeInfo).IsSuccess() {
+			return true, nil
+		}
+		if err := removePod(podInfo); err != nil {
+			return false, err
+		}
+		candidate.victims = append(candidate.victims, podInfo.Pod)
+		if !misplaced[podInfo.Pod.UID] {
+			candidate.preempted++
+		}
+		return false, nil
+	}
+	violatingVictims, nonViolatingVictims := filterPodsWithPDBViolation(potentialVictims, pdbs)
+	for _, podInfo := range violatingVictims {
+		reprieved, err := reprieve(podInfo)
+		if err != nil {
+			return nil, err
+		}
+		if !reprieved {
+			candidate.pdbViolations++
+		}
+	}
+	for _, podInfo := range nonViolatingVictims {
+		if _, err := reprieve(podInfo); err != nil {
+			return nil, err
+		}
+	}
+	return candidate, nil
+}
+
+// filterPodsWithPDBViolation splits the pods, keeping their order, into the pods whose pod disruption
+// budget would be violated if they were all preempted and the other pods, as in the default preemption
+func filterPodsWithPDBViolation(podInfos []*framework.PodInfo, pdbs []*policyv1.PodDisruptionBudget) ([]*framework.PodInfo, []*framework.PodInfo) {
+	pdbsAllowed := make([]int32, len(pdbs))
+	for i, pdb := range pdbs {
+		pdbsAllowed[i] = pdb.Status.DisruptionsAllowed
+	}
+	var violatingPodInfos, nonViolatingPodInfos []*framework.PodInfo
+	for _, podInfo := range podInfos {
+		pod := podInfo.Pod
+		violated := false
+		// a pod without labels doesn't match any pod disruption budget
+		if len(pod.Labels) != 0 {
+			for i, pdb := range pdbs {
+				if pdb.Namespace != pod.Namespace {
+					continue
+				}
+				selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+				// a pod disruption budget with a nil or empty selector matches no pod
+				if err != nil || selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+					continue
+				}
+				// the pods already disrupted were processed by the API server and don't count
+				if _, ok := pdb.Status.DisruptedPods[pod.Name]; ok {
+					continue
+				}
+				pdbsAllowed[i]--
+				if pdbsAllowed[i] < 0 {
+					violated = true
+				}
+			}
+		}
+		if violated {
+			violatingPodInfos = append(violatingPodInfos, podInfo)
+		} else {
+			nonViolatingPodInfos = append(nonViolatingPodInfos, podInfo)
+		}
+	}
+	return violatingPodInfos, nonViolatingPodInfos
+}
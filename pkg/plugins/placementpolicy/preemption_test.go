@@ -0,0 +1,427 @@
+package placementpolicy
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/metrics"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	policylisters "k8s.io/client-go/listers/policy/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+// podCountFilter is a filter plugin rejecting the nodes running as many pods as their allocatable pods
+type podCountFilter struct{}
+
+const podCountFilterName = "PodCount"
+
+func (f *podCountFilter) Name() string {
+	return podCountFilterName
+}
+
+func (f *podCountFilter) Filter(_ context.Context, _ *framework.CycleState, _ *corev1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	if int64(len(nodeInfo.Pods)) >= nodeInfo.Node().Status.Allocatable.Pods().Value() {
+		return framework.NewStatus(framework.Unschedulable, "too many pods")
+	}
+	return nil
+}
+
+// fakeManager is a core.Manager returning a static placement policy and its pods
+type fakeManager struct {
+	pp   *v1alpha1.PlacementPolicy
+	pods []*corev1.Pod
+}
+
+func (m *fakeManager) GetPlacementPolicyForPod(context.Context, *corev1.Pod) (*v1alpha1.PlacementPolicy, error) {
+	return m.pp, nil
+}
+
+func (m *fakeManager) GetPlacementPoliciesForPod(context.Context, *corev1.Pod) ([]*v1alpha1.PlacementPolicy, error) {
+	return []*v1alpha1.PlacementPolicy{m.pp}, nil
+}
+
+func (m *fakeManager) GetPodsForPlacementPolicy(context.Context, *v1alpha1.PlacementPolicy) ([]*corev1.Pod, error) {
+	return m.pods, nil
+}
+
+func (m *fakeManager) AnnotatePod(context.Context, *corev1.Pod, *v1alpha1.PlacementPolicy, bool) error {
+	return nil
+}
+
+func (m *fakeManager) GetPlacementPolicy(context.Context, string, string) (*v1alpha1.PlacementPolicy, error) {
+	return m.pp, nil
+}
+
+// fakePodNominator is a framework.PodNominator without nominated pods
+type fakePodNominator struct{}
+
+func (n *fakePodNominator) AddNominatedPod(*framework.PodInfo, string) {}
+
+func (n *fakePodNominator) DeleteNominatedPodIfExists(*corev1.Pod) {}
+
+func (n *fakePodNominator) UpdateNominatedPod(*corev1.Pod, *framework.PodInfo) {}
+
+func (n *fakePodNominator) NominatedPodsForNode(string) []*framework.PodInfo {
+	return nil
+}
+
+func TestPostFilterPreemption(t *testing.T) {
+	metrics.Register()
+	makeNode := func(name, label string, pods int64) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"node": label}},
+			Status:     corev1.NodeStatus{Allocatable: corev1.ResourceList{corev1.ResourcePods: *resource.NewQuantity(pods, resource.DecimalSI)}},
+		}
+	}
+	controller := true
+	makePod := func(name, owner, nodeName string, priority int32) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name)},
+			Spec:       corev1.PodSpec{NodeName: nodeName, Priority: &priority},
+		}
+		if owner != "" {
+			pod.Labels = map[string]string{"app": "nginx"}
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: owner, UID: types.UID(owner), Controller: &controller}}
+		}
+		return pod
+	}
+	targetSize := intstr.FromString("50%")
+	pp := &v1alpha1.PlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "default"},
+		Spec: v1alpha1.PlacementPolicySpec{
+			Weight:          v1alpha1.DefaultWeight,
+			EnforcementMode: v1alpha1.EnforcementModeStrict,
+			PodSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+			NodeSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+			Policy: &v1alpha1.Policy{
+				Action:     v1alpha1.ActionMust,
+				TargetSize: &targetSize,
+				Rounding:   v1alpha1.RoundingModeUp,
+				Scope:      v1alpha1.ScopePerOwner,
+			},
+		},
+	}
+	// the pod of rs-a prefers the nodes with matching labels, where it has no pods yet
+	pod := makePod("a1", "rs-a", "", 10)
+
+	tests := []struct {
+		name          string
+		pods          []*corev1.Pod
+		pdbs          []*policyv1.PodDisruptionBudget
+		wantNode      string
+		wantPreempted []string
+	}{
+		{
+			name: "lower priority pod preempted",
+			pods: []*corev1.Pod{
+				makePod("low", "", "node1", 0),
+				makePod("high1", "", "node1", 100),
+				makePod("high2", "", "node2", 100),
+			},
+			wantNode:      "node1",
+			wantPreempted: []string{"low"},
+		},
+		{
+			name: "misplaced pod of the placement policy preempted over lower priority pod",
+			pods: []*corev1.Pod{
+				// rs-b has both its pods on the nodes with matching labels, over its 50% target
+				makePod("b1", "rs-b", "node1", 10),
+				makePod("low", "", "node1", 0),
+				makePod("b2", "rs-b", "node2", 10),
+			},
+			wantNode:      "node1",
+			wantPreempted: []string{"b1"},
+		},
+		{
+			name: "misplaced pod of the placement policy with a higher priority not preempted",
+			pods: []*corev1.Pod{
+				makePod("b1", "rs-b", "node1", 100),
+				makePod("low", "", "node1", 0),
+				makePod("b2", "rs-b", "node2", 100),
+			},
+			wantNode:      "node1",
+			wantPreempted: []string{"low"},
+		},
+		{
+			name: "pod disruption budget violations avoided",
+			pods: []*corev1.Pod{
+				func() *corev1.Pod {
+					pod := makePod("low1", "", "node1", 0)
+					pod.Labels = map[string]string{"app": "redis"}
+					return pod
+				}(),
+				makePod("high1", "", "node1", 100),
+				makePod("low2", "", "node2", 0),
+			},
+			pdbs: []*policyv1.PodDisruptionBudget{{
+				ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default"},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "redis"}}},
+			}},
+			wantNode:      "node2",
+			wantPreempted: []string{"low2"},
+		},
+		{
+			name: "pods of the placement policy on their preferred nodes not preempted",
+			pods: []*corev1.Pod{
+				// rs-c has one pod on the nodes with matching labels, at its 50% target
+				makePod("c1", "rs-c", "node1", 0),
+				makePod("high1", "", "node1", 100),
+				makePod("c2", "rs-c", "node3", 0),
+				makePod("high2", "", "node2", 100),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			nodes := []*corev1.Node{
+				makeNode("node1", "want", 2),
+				makeNode("node2", "want", 1),
+				makeNode("node3", "unwant", 10),
+			}
+			lister := newFakeSharedLister(nodes)
+			var objs []runtime.Object
+			policyPods := []*corev1.Pod{pod}
+			for _, other := range tt.pods {
+				nodeInfo, err := lister.Get(other.Spec.NodeName)
+				if err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				nodeInfo.AddPod(other)
+				objs = append(objs, other)
+				if other.Labels["app"] == "nginx" {
+					policyPods = append(policyPods, other)
+				}
+			}
+			client := fake.NewSimpleClientset(objs...)
+			pdbIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, pdb := range tt.pdbs {
+				if err := pdbIndexer.Add(pdb); err != nil {
+					t.Fatalf("failed to add pod disruption budget: %v", err)
+				}
+			}
+
+			p := &Plugin{
+				ppMgr:             &fakeManager{pp: pp, pods: policyPods},
+				assumedPlacements: core.NewAssumedPlacementCache(),
+				scoreMagnitude:    framework.MaxNodeScore,
+				enablePreemption:  true,
+				eventRecorder:     events.NewFakeRecorder(10),
+				pdbLister:         policylisters.NewPodDisruptionBudgetLister(pdbIndexer),
+			}
+			registry := frameworkruntime.Registry{
+				queuesort.Name:     queuesort.New,
+				defaultbinder.Name: defaultbinder.New,
+				podCountFilterName: func(runtime.Object, framework.Handle) (framework.Plugin, error) { return &podCountFilter{}, nil },
+				Name:               func(runtime.Object, framework.Handle) (framework.Plugin, error) { return p, nil },
+			}
+			profile := &config.KubeSchedulerProfile{
+				SchedulerName: "test",
+				Plugins: &config.Plugins{
+					QueueSort: config.PluginSet{Enabled: []config.Plugin{{Name: queuesort.Name}}},
+					Bind:      config.PluginSet{Enabled: []config.Plugin{{Name: defaultbinder.Name}}},
+					Filter:    config.PluginSet{Enabled: []config.Plugin{{Name: podCountFilterName}, {Name: Name}}},
+				},
+			}
+			fh, err := frameworkruntime.NewFramework(registry, profile,
+				frameworkruntime.WithSnapshotSharedLister(lister),
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithPodNominator(&fakePodNominator{}))
+			if err != nil {
+				t.Fatalf("NewFramework() error = %v", err)
+			}
+			p.frameworkHandler = fh
+
+			state := framework.NewCycleState()
+			if status := p.PreFilter(ctx, state, pod); !status.IsSuccess() {
+				t.Fatalf("PreFilter() status = %v", status)
+			}
+			filteredNodeStatusMap := make(framework.NodeToStatusMap)
+			for _, nodeInfo := range lister.nodeInfos {
+				status := fh.RunFilterPlugins(ctx, state, pod, nodeInfo).Merge()
+				if status.IsSuccess() {
+					t.Fatalf("RunFilterPlugins() on node %s status = %v, want all nodes filtered", nodeInfo.Node().Name, status)
+				}
+				filteredNodeStatusMap[nodeInfo.Node().Name] = status
+			}
+
+			result, status := p.PostFilter(ctx, state, pod, filteredNodeStatusMap)
+			if tt.wantNode == "" {
+				if status.Code() != framework.Unschedulable {
+					t.Errorf("PostFilter() status = %v, want %v", status.Code(), framework.Unschedulable)
+				}
+			} else if !status.IsSuccess() || result == nil || result.NominatedNodeName != tt.wantNode {
+				t.Errorf("PostFilter() = %v, %v, want node %s", result, status, tt.wantNode)
+			}
+
+			podList, err := client.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			remaining := make(map[string]bool)
+			for _, other := range podList.Items {
+				remaining[other.Name] = true
+			}
+			var preempted []string
+			for _, other := range tt.pods {
+				if !remaining[other.Name] {
+					preempted = append(preempted, other.Name)
+				}
+			}
+			sort.Strings(preempted)
+			if !reflect.DeepEqual(preempted, tt.wantPreempted) {
+				t.Errorf("PostFilter() preempted %v, want %v", preempted, tt.wantPreempted)
+			}
+		})
+	}
+}
+
+func TestPreFilterExtensions(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"node": "want"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"node": "unwant"}}},
+	}
+	makePod := func(name, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name), Labels: map[string]string{"app": "nginx"}},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+		}
+	}
+	pod := makePod("pod", "")
+	// the pod of the placement policy nominated on a node isn't scheduled yet
+	nominated := makePod("nominated", "")
+	pods := []*corev1.Pod{pod, nominated, makePod("a", "node1"), makePod("b", "node1"), makePod("c", "node2")}
+	other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default", UID: "other"}, Spec: corev1.PodSpec{NodeName: "node1"}}
+	targetSize := intstr.FromString("40%")
+	pp := &v1alpha1.PlacementPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "default"},
+		Spec: v1alpha1.PlacementPolicySpec{
+			Weight:          v1alpha1.DefaultWeight,
+			EnforcementMode: v1alpha1.EnforcementModeStrict,
+			PodSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+			NodeSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+			Policy: &v1alpha1.Policy{
+				Action:     v1alpha1.ActionMust,
+				TargetSize: &targetSize,
+				Rounding:   v1alpha1.RoundingModeDown,
+			},
+		},
+	}
+
+	type change struct {
+		add  bool
+		pod  *corev1.Pod
+		node int
+	}
+	tests := []struct {
+		name    string
+		changes []change
+		// wantFits is whether the pod fits on the node with matching labels after the changes
+		wantFits bool
+	}{
+		{
+			// 2 of 5 pods on the node with matching labels, at the 40% target
+			name: "no change",
+		},
+		{
+			// 1 of 4 pods on the node with matching labels, below the 40% target of 1 pod
+			// rounded down, but the pod would exceed it
+			name:    "pod removed from the nodes with matching labels",
+			changes: []change{{pod: pods[3], node: 0}},
+		},
+		{
+			// 2 of 4 pods on the node with matching labels, over the 40% target of 1 pod
+			name:    "pod removed from the other nodes",
+			changes: []change{{pod: pods[4], node: 1}},
+		},
+		{
+			// 0 of 3 pods on the node with matching labels, below the 40% target of 1 pod
+			name:     "pods removed from the nodes with matching labels",
+			changes:  []change{{pod: pods[2], node: 0}, {pod: pods[3], node: 0}},
+			wantFits: true,
+		},
+		{
+			name:    "removed pod added back",
+			changes: []change{{pod: pods[2], node: 0}, {pod: pods[3], node: 0}, {add: true, pod: pods[3], node: 0}},
+		},
+		{
+			// 2 of 4 pods on the node with matching labels, with the nominated pod counted on the other nodes
+			name:    "nominated pod added to the other nodes",
+			changes: []change{{add: true, pod: nominated, node: 1}, {pod: pods[4], node: 1}},
+		},
+		{
+			name:     "nominated pod added and removed",
+			changes:  []change{{add: true, pod: nominated, node: 0}, {pod: nominated, node: 0}, {pod: pods[2], node: 0}, {pod: pods[3], node: 0}},
+			wantFits: true,
+		},
+		{
+			name:    "pod not counted by the placement policy removed",
+			changes: []change{{pod: other, node: 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fh, err := frameworkruntime.NewFramework(nil, nil, frameworkruntime.WithSnapshotSharedLister(newFakeSharedLister(nodes)))
+			if err != nil {
+				t.Fatalf("NewFramework() error = %v", err)
+			}
+			p := &Plugin{
+				frameworkHandler:  fh,
+				ppMgr:             &fakeManager{pp: pp, pods: pods},
+				assumedPlacements: core.NewAssumedPlacementCache(),
+				scoreMagnitude:    framework.MaxNodeScore,
+			}
+			state := framework.NewCycleState()
+			if status := p.PreFilter(ctx, state, pod); !status.IsSuccess() {
+				t.Fatalf("PreFilter() status = %v", status)
+			}
+
+			// the changes are made on a copy of the state, as by the preemption
+			simulated := state.Clone()
+			for _, c := range tt.changes {
+				nodeInfo := framework.NewNodeInfo()
+				nodeInfo.SetNode(nodes[c.node])
+				podInfo := framework.NewPodInfo(c.pod)
+				var status *framework.Status
+				if c.add {
+					status = p.PreFilterExtensions().AddPod(ctx, simulated, pod, podInfo, nodeInfo)
+				} else {
+					status = p.PreFilterExtensions().RemovePod(ctx, simulated, pod, podInfo, nodeInfo)
+				}
+				if !status.IsSuccess() {
+					t.Fatalf("AddPod() or RemovePod() status = %v", status)
+				}
+			}
+
+			nodeInfo := framework.NewNodeInfo()
+			nodeInfo.SetNode(nodes[0])
+			if got := p.Filter(ctx, simulated, pod, nodeInfo).IsSuccess(); got != tt.wantFits {
+				t.Errorf("Filter() after the changes fits = %v, want %v", got, tt.wantFits)
+			}
+			if p.Filter(ctx, state, pod, nodeInfo).IsSuccess() {
+				t.Errorf("Filter() on the original state fits, want the changes made on the copy only")
+			}
+		})
+	}
+}
@@ -1,23 +1,280 @@
 package placementpolicy
 
 import (
+	"fmt"
+
 	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 type stateData struct {
 	name string
 	pp   *v1alpha1.PlacementPolicy
+	// nodeSelector is the compiled node selector of the placement policy
+	nodeSelector labels.Selector
+	// preferredNodeWithMatchingLabels is true if the pod should be placed on
+	// a node matching the node selector of the placement policy
+	preferredNodeWithMatchingLabels bool
+	// preferenceScore is the score of the nodes of the preferred group
+	preferenceScore int64
+	// conflictingPolicies are the names of the other placement policies
+	// matching the pod with the same weight
+	conflictingPolicies []string
+	// topologySpread is the number of pods of the preferred node group in each
+	// topology domain, nil if the placement policy doesn't spread the pods
+	topologySpread *topologySpread
+	// groupSelectors are the compiled node selectors of the node groups of the
+	// placement policy, nil if the placement policy doesn't have node groups
+	groupSelectors []labels.Selector
+	// preferredGroup is the index of the node group the pod should be placed
+	// on, -1 for the nodes outside of the node groups
+	preferredGroup int
+	// counts are the numbers of pods counted by the placement policy the node
+	// preference is computed from, nil if the state can't be updated
+	counts *placementCounts
+}
+
+// placementCounts are the numbers of pods counted by the placement policy on each group of nodes.
+// They are updated by AddPod and RemovePod, when the preemption simulates the removal of pods from
+// a node, so that the node preference of the pod follows the pods that would remain.
+type placementCounts struct {
+	// podsInGroup is the number of pods on each group of nodes: the nodes with labels matching the
+	// node selector followed by the other nodes, or the node groups followed by the nodes outside of them
+	podsInGroup []int
+	// totalPods is the number of pods the target sizes are computed from
+	totalPods int
+	// placed returns whether the pod was counted on a node when the state was computed, and whether
+	// it's counted by the placement policy at all. It's shared by the clones of the state.
+	placed func(types.UID) (bool, bool)
+	// changes are the pods added to (true) or removed from (false) the nodes since the state was computed
+	changes map[types.UID]bool
+	// spreads are the topology spreads of each group of nodes, nil if the placement policy doesn't
+	// spread the pods across topology domains
+	spreads []*topologySpread
+	// scoreMagnitude is the score of the nodes of the preferred group at the largest deviation
+	scoreMagnitude int64
+}
+
+// newPlacementCounts counts the pods of podsInGroup on each group of nodes, out of the pods of podList
+// other than the pod being scheduled
+func newPlacementCounts(pod *corev1.Pod, podList []*corev1.Pod, podsInGroup [][]*corev1.Pod, spreads []*topologySpread, scoreMagnitude int64) *placementCounts {
+	placed := make(map[types.UID]bool, len(podList))
+	for _, other := range podList {
+		if other.UID != pod.UID {
+			placed[other.UID] = false
+		}
+	}
+	c := &placementCounts{
+		podsInGroup: make([]int, 0, len(podsInGroup)),
+		totalPods:   len(podList),
+		placed: func(uid types.UID) (bool, bool) {
+			onNode, ok := placed[uid]
+			return onNode, ok
+		},
+		changes:        make(map[types.UID]bool),
+		spreads:        spreads,
+		scoreMagnitude: scoreMagnitude,
+	}
+	for _, pods := range podsInGroup {
+		c.podsInGroup = append(c.podsInGroup, len(pods))
+		for _, other := range pods {
+			placed[other.UID] = true
+		}
+	}
+	return c
 }
 
-func NewStateData(name string, pp *v1alpha1.PlacementPolicy) framework.StateData {
+func (c *placementCounts) clone() *placementCounts {
+	clone := &placementCounts{
+		podsInGroup:    make([]int, len(c.podsInGroup)),
+		totalPods:      c.totalPods,
+		placed:         c.placed,
+		changes:        make(map[types.UID]bool, len(c.changes)),
+		scoreMagnitude: c.scoreMagnitude,
+	}
+	copy(clone.podsInGroup, c.podsInGroup)
+	for uid, onNode := range c.changes {
+		clone.changes[uid] = onNode
+	}
+	if c.spreads != nil {
+		clone.spreads = make([]*topologySpread, 0, len(c.spreads))
+		for _, spread := range c.spreads {
+			clone.spreads = append(clone.spreads, spread.clone())
+		}
+	}
+	return clone
+}
+
+// onNode returns whether the pod is counted on a node, and whether it's counted by the placement policy at all
+func (c *placementCounts) onNode(uid types.UID) (bool, bool) {
+	placed, ok := c.placed(uid)
+	if !ok {
+		return false, false
+	}
+	if onNode, changed := c.changes[uid]; changed {
+		return onNode, true
+	}
+	return placed, true
+}
+
+// move adds delta pods to the group and to the topology domain of the node
+func (c *placementCounts) move(group int, node *corev1.Node, delta int) {
+	c.podsInGroup[group] += delta
+	if c.spreads != nil {
+		c.spreads[group].add(node, delta)
+	}
+}
+
+func NewStateData(name string, pp *v1alpha1.PlacementPolicy, nodeSelector labels.Selector, preferredNodeWithMatchingLabels bool, preferenceScore int64, conflictingPolicies []string, topologySpread *topologySpread) framework.StateData {
 	return &stateData{
-		name: name,
-		pp:   pp,
+		name:                            name,
+		pp:                              pp,
+		nodeSelector:                    nodeSelector,
+		preferredNodeWithMatchingLabels: preferredNodeWithMatchingLabels,
+		preferenceScore:                 preferenceScore,
+		conflictingPolicies:             conflictingPolicies,
+		topologySpread:                  topologySpread,
 	}
 }
 
+// Clone copies the counts of the state, so that the pods added and removed by the preemption in a
+// copy of the cycle state don't change the node preference of the original one
 func (d *stateData) Clone() framework.StateData {
-	return d
+	if d.counts == nil {
+		return d
+	}
+	clone := *d
+	clone.counts = d.counts.clone()
+	if clone.counts.spreads != nil {
+		clone.topologySpread = clone.counts.spreads[clone.preferredIndex()]
+	}
+	return &clone
+}
+
+// addPod counts the pod of the placement policy added to the node and updates the node preference.
+// The pods removed before are counted again, and the pods not scheduled yet, such as the pods
+// nominated on the node, are counted on the node.
+func (d *stateData) addPod(pod *corev1.Pod, node *corev1.Node) error {
+	if d.counts == nil {
+		return nil
+	}
+	onNode, ok := d.counts.onNode(pod.UID)
+	if !ok || onNode {
+		return nil
+	}
+	if removed, changed := d.counts.changes[pod.UID]; changed && !removed {
+		d.counts.totalPods++
+	}
+	d.counts.changes[pod.UID] = true
+	d.counts.move(d.groupIndex(node), node, 1)
+	return d.updatePreference()
+}
+
+// removePod stops counting the pod of the placement policy removed from the node and updates the
+// node preference. The pods that were not scheduled when the state was computed are counted as
+// not scheduled again, the others are no longer counted at all.
+func (d *stateData) removePod(pod *corev1.Pod, node *corev1.Node) error {
+	if d.counts == nil {
+		return nil
+	}
+	onNode, ok := d.counts.onNode(pod.UID)
+	if !ok || !onNode {
+		return nil
+	}
+	if placed, _ := d.counts.placed(pod.UID); placed {
+		d.counts.changes[pod.UID] = false
+		d.counts.totalPods--
+	} else {
+		delete(d.counts.changes, pod.UID)
+	}
+	d.counts.move(d.groupIndex(node), node, -1)
+	return d.updatePreference()
+}
+
+// updatePreference determines the node preference of the pod from the counts of the state
+func (d *stateData) updatePreference() error {
+	c := d.counts
+	if d.groupSelectors != nil {
+		preferredGroup, deficit, err := getNodeGroupPreference(d.pp.Spec.Policy.Groups, c.podsInGroup, c.totalPods)
+		if err != nil {
+			return err
+		}
+		d.nodeSelector, d.preferredNodeWithMatchingLabels, d.preferredGroup = labels.Nothing(), false, -1
+		if preferredGroup < len(d.groupSelectors) {
+			d.nodeSelector, d.preferredNodeWithMatchingLabels, d.preferredGroup = d.groupSelectors[preferredGroup], true, preferredGroup
+		}
+		d.preferenceScore = scaleNodeScore(deficit, c.totalPods, c.scoreMagnitude)
+	} else {
+		targetSize, err := core.GetTargetSize(d.pp, c.totalPods)
+		if err != nil {
+			return fmt.Errorf("failed to get scaled value from int or percent: %v", err)
+		}
+		d.preferredNodeWithMatchingLabels, d.preferenceScore = getNodePreference(c.podsInGroup[0], targetSize, c.totalPods, c.scoreMagnitude)
+	}
+	if c.spreads != nil {
+		d.topologySpread = c.spreads[d.preferredIndex()]
+	}
+	return nil
+}
+
+// groupIndex returns the index of the group of nodes the node belongs to in the counts of the state
+func (d *stateData) groupIndex(node *corev1.Node) int {
+	if d.groupSelectors != nil {
+		if group := core.GetNodeGroup(node, d.groupSelectors); group >= 0 {
+			return group
+		}
+		return len(d.groupSelectors)
+	}
+	if d.nodeSelector.Matches(labels.Set(node.Labels)) {
+		return 0
+	}
+	return 1
+}
+
+// preferredIndex returns the index of the group of nodes the pod should be placed on in the counts of the state
+func (d *stateData) preferredIndex() int {
+	if d.groupSelectors != nil {
+		if d.preferredGroup < 0 {
+			return len(d.groupSelectors)
+		}
+		return d.preferredGroup
+	}
+	if d.preferredNodeWithMatchingLabels {
+		return 0
+	}
+	return 1
+}
+
+// misplaced returns true if a pod counted by the state on the node would be placed on another group
+// of nodes if it was scheduled again, i.e. if it was no longer counted on the node
+func (d *stateData) misplaced(node *corev1.Node) (bool, error) {
+	clone := d.Clone().(*stateData)
+	clone.counts.move(clone.groupIndex(node), node, -1)
+	if err := clone.updatePreference(); err != nil {
+		return false, err
+	}
+	return !clone.inPreferredGroup(node), nil
+}
+
+// inPreferredGroup returns true if the node belongs to the group of nodes the pod should be placed on
+func (d *stateData) inPreferredGroup(node *corev1.Node) bool {
+	if d.groupSelectors != nil {
+		return core.GetNodeGroup(node, d.groupSelectors) == d.preferredGroup
+	}
+	// nodeMatchesLabels is set to true if the node matches the node selector defined in the placement policy
+	nodeMatchesLabels := d.nodeSelector.Matches(labels.Set(node.Labels))
+	return nodeMatchesLabels == d.preferredNodeWithMatchingLabels
+}
+
+// nodeGroupName returns the name of the node group of the placement policy the node belongs to
+func (d *stateData) nodeGroupName(node *corev1.Node) string {
+	if group := core.GetNodeGroup(node, d.groupSelectors); group >= 0 {
+		return d.pp.Spec.Policy.Groups[group].Name
+	}
+	return "<none>"
 }
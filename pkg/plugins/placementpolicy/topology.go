@@ -0,0 +1,141 @@
+package placementpolicy
+
+import (
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// topologySpread is the number of pods of a group of nodes in each
+// topology domain of the group
+type topologySpread struct {
+	// topologyKey is the key of the node labels whose values are the topology domains
+	topologyKey string
+	// podsInDomain is the number of pods in each topology domain of the group
+	podsInDomain map[string]int
+	// minPods is the number of pods in the least populated domain
+	minPods int
+	// maxPods is the number of pods in the most populated domain
+	maxPods int
+}
+
+// newTopologySpread counts the pods placed on the node group in each topology domain of the nodes
+// of the group. The domains of the group without any pod are counted as well, while the pods on
+// nodes without the topology key label, or on nodes that are no longer in the snapshot, are not.
+func newTopologySpread(topologyKey string, groupNodes []*corev1.Node, groupPods []*corev1.Pod, nodeInfos framework.NodeInfoLister, assumedPlacements *core.AssumedPlacementCache) *topologySpread {
+	s := &topologySpread{
+		topologyKey:  topologyKey,
+		podsInDomain: make(map[string]int),
+	}
+	for _, node := range groupNodes {
+		if domain, ok := node.Labels[topologyKey]; ok {
+			s.podsInDomain[domain] = 0
+		}
+	}
+
+	for _, pod := range groupPods {
+		// the pods reserved on a node but not bound yet are counted on the node of the assumed placement
+		nodeName := getPodNodeName(pod, assumedPlacements)
+		if nodeName == "" {
+			continue
+		}
+		nodeInfo, err := nodeInfos.Get(nodeName)
+		if err != nil || nodeInfo.Node() == nil {
+			continue
+		}
+		domain, ok := nodeInfo.Node().Labels[topologyKey]
+		if !ok {
+			continue
+		}
+		if _, ok := s.podsInDomain[domain]; ok {
+			s.podsInDomain[domain]++
+		}
+	}
+
+	s.updateBounds()
+	return s
+}
+
+// getTopologySpreads returns the topology spread of each group of nodes of the counts of the state,
+// or nil if the placement policy doesn't spread the pods across topology domains. podsInGroup are
+// the pods placed on each group of nodes.
+func (p *Plugin) getTopologySpreads(d *stateData, nodeList []*corev1.Node, podsInGroup [][]*corev1.Pod) []*topologySpread {
+	if d.pp.Spec.Policy.TopologyKey == "" {
+		return nil
+	}
+	groupNodes := make([][]*corev1.Node, len(podsInGroup))
+	for _, node := range nodeList {
+		group := d.groupIndex(node)
+		groupNodes[group] = append(groupNodes[group], node)
+	}
+	nodeInfos := p.frameworkHandler.SnapshotSharedLister().NodeInfos()
+	spreads := make([]*topologySpread, 0, len(podsInGroup))
+	for group, groupPods := range podsInGroup {
+		spreads = append(spreads, newTopologySpread(d.pp.Spec.Policy.TopologyKey, groupNodes[group], groupPods, nodeInfos, p.assumedPlacements))
+	}
+	return spreads
+}
+
+func (s *topologySpread) clone() *topologySpread {
+	clone := *s
+	clone.podsInDomain = make(map[string]int, len(s.podsInDomain))
+	for domain, pods := range s.podsInDomain {
+		clone.podsInDomain[domain] = pods
+	}
+	return &clone
+}
+
+// add adds delta pods to the topology domain of the node, if it's a domain of the group
+func (s *topologySpread) add(node *corev1.Node, delta int) {
+	domain, ok := node.Labels[s.topologyKey]
+	if !ok {
+		return
+	}
+	if _, ok := s.podsInDomain[domain]; !ok {
+		return
+	}
+	s.podsInDomain[domain] += delta
+	s.updateBounds()
+}
+
+// updateBounds updates the number of pods in the least and the most populated domains
+func (s *topologySpread) updateBounds() {
+	first := true
+	for _, pods := range s.podsInDomain {
+		if first || pods < s.minPods {
+			s.minPods = pods
+		}
+		if first || pods > s.maxPods {
+			s.maxPods = pods
+		}
+		first = false
+	}
+}
+
+// fits returns true if placing the pod on the node keeps the difference between the number of
+// pods in the most and the least populated domains of the group at most one
+func (s *topologySpread) fits(node *corev1.Node) bool {
+	domain, ok := node.Labels[s.topologyKey]
+	if !ok {
+		return false
+	}
+	return s.podsInDomain[domain] <= s.minPods
+}
+
+// score scales down the score of the preferred node group for the nodes of the domains with more
+// pods than the least populated domain. The nodes without the topology key label get the lowest
+// score of the group.
+func (s *topologySpread) score(node *corev1.Node, preferenceScore int64) int64 {
+	domain, ok := node.Labels[s.topologyKey]
+	if !ok {
+		return framework.MinNodeScore + 1
+	}
+	spread := int64(s.maxPods - s.minPods + 1)
+	score := preferenceScore * (spread - int64(s.podsInDomain[domain]-s.minPods)) / spread
+	// the nodes of the preferred group are always preferred over the other nodes
+	if score < framework.MinNodeScore+1 {
+		score = framework.MinNodeScore + 1
+	}
+	return score
+}
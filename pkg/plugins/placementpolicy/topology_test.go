@@ -0,0 +1,154 @@
+package placementpolicy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const testTopologyKey = "topology.kubernetes.io/zone"
+
+func newTestZoneNode(name, zone string) *corev1.Node {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"node": "want"}}}
+	if zone != "" {
+		node.Labels[testTopologyKey] = zone
+	}
+	return node
+}
+
+func TestNewTopologySpread(t *testing.T) {
+	nodes := []*corev1.Node{
+		newTestZoneNode("node1", "zone1"),
+		newTestZoneNode("node2", "zone1"),
+		newTestZoneNode("node3", "zone2"),
+		newTestZoneNode("node4", "zone3"),
+		newTestZoneNode("node5", ""),
+	}
+	nodeInfos := newFakeSharedLister(nodes).NodeInfos()
+	makePod := func(name, nodeName string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)}, Spec: corev1.PodSpec{NodeName: nodeName}}
+	}
+
+	tests := []struct {
+		name              string
+		groupNodes        []*corev1.Node
+		groupPods         []*corev1.Pod
+		assumedPlacements map[types.UID]core.AssumedPlacement
+		want              *topologySpread
+	}{
+		{
+			name:       "no pods",
+			groupNodes: nodes,
+			want: &topologySpread{
+				topologyKey:  testTopologyKey,
+				podsInDomain: map[string]int{"zone1": 0, "zone2": 0, "zone3": 0},
+			},
+		},
+		{
+			name:       "pods counted per domain",
+			groupNodes: nodes,
+			groupPods: []*corev1.Pod{
+				makePod("pod1", "node1"),
+				makePod("pod2", "node2"),
+				makePod("pod3", "node3"),
+				// pods on nodes without the label or no longer in the snapshot are not counted
+				makePod("pod4", "node5"),
+				makePod("pod5", "node6"),
+			},
+			want: &topologySpread{
+				topologyKey:  testTopologyKey,
+				podsInDomain: map[string]int{"zone1": 2, "zone2": 1, "zone3": 0},
+				minPods:      0,
+				maxPods:      2,
+			},
+		},
+		{
+			name:       "assumed placements counted on their node",
+			groupNodes: nodes[:3],
+			groupPods: []*corev1.Pod{
+				makePod("pod1", "node1"),
+				makePod("pod2", ""),
+				makePod("pod3", ""),
+				// pods on nodes of domains outside of the group are not counted
+				makePod("pod4", "node4"),
+			},
+			assumedPlacements: map[types.UID]core.AssumedPlacement{
+				"pod2": {PolicyName: "pp", NodeName: "node3", NodeWithMatchingLabels: true},
+			},
+			want: &topologySpread{
+				topologyKey:  testTopologyKey,
+				podsInDomain: map[string]int{"zone1": 1, "zone2": 1},
+				minPods:      1,
+				maxPods:      1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assumedPlacements := core.NewAssumedPlacementCache()
+			for uid, placement := range tt.assumedPlacements {
+				assumedPlacements.Assume(uid, placement)
+			}
+			got := newTopologySpread(testTopologyKey, tt.groupNodes, tt.groupPods, nodeInfos, assumedPlacements)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newTopologySpread() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopologySpreadFitsAndScore(t *testing.T) {
+	s := &topologySpread{
+		topologyKey:  testTopologyKey,
+		podsInDomain: map[string]int{"zone1": 3, "zone2": 2, "zone3": 1},
+		minPods:      1,
+		maxPods:      3,
+	}
+
+	tests := []struct {
+		name      string
+		node      *corev1.Node
+		wantFits  bool
+		wantScore int64
+	}{
+		{
+			name:      "least populated domain",
+			node:      newTestZoneNode("node1", "zone3"),
+			wantFits:  true,
+			wantScore: 90,
+		},
+		{
+			name:      "domain with one more pod",
+			node:      newTestZoneNode("node2", "zone2"),
+			wantScore: 60,
+		},
+		{
+			name:      "most populated domain",
+			node:      newTestZoneNode("node3", "zone1"),
+			wantScore: 30,
+		},
+		{
+			name:      "node without the topology key",
+			node:      newTestZoneNode("node4", ""),
+			wantScore: framework.MinNodeScore + 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.fits(tt.node); got != tt.wantFits {
+				t.Errorf("fits() = %t, want %t", got, tt.wantFits)
+			}
+			if got := s.score(tt.node, 90); got != tt.wantScore {
+				t.Errorf("score() = %d, want %d", got, tt.wantScore)
+			}
+		})
+	}
+}
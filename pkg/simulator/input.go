@@ -0,0 +1,126 @@
+package simulator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+var (
+	scheme = runtime.NewScheme()
+	codecs = serializer.NewCodecFactory(scheme)
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+}
+
+// Input is the cluster state the placement is simulated against.
+type Input struct {
+	Nodes                    []*corev1.Node
+	Pods                     []*corev1.Pod
+	Namespaces               []*corev1.Namespace
+	PlacementPolicies        []*v1alpha1.PlacementPolicy
+	ClusterPlacementPolicies []*v1alpha1.ClusterPlacementPolicy
+}
+
+// LoadFiles reads the nodes, pods, namespaces and placement policies of the YAML or JSON
+// files. A file can contain multiple documents and lists, such as the output of
+// kubectl get nodes,pods,placementpolicies -A -o yaml. Other kinds of objects are ignored.
+func LoadFiles(paths ...string) (*Input, error) {
+	input := &Input{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		err = input.load(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", path, err)
+		}
+	}
+	return input, nil
+}
+
+// load adds the objects of the YAML or JSON documents read from r to the input
+func (in *Input) load(r io.Reader) error {
+	reader := utilyaml.NewYAMLOrJSONDecoder(bufio.NewReader(r), 4096)
+	for {
+		raw := runtime.RawExtension{}
+		if err := reader.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if len(raw.Raw) == 0 || string(raw.Raw) == "null" {
+			continue
+		}
+		obj, _, err := codecs.UniversalDeserializer().Decode(raw.Raw, nil, nil)
+		if err != nil {
+			if runtime.IsNotRegisteredError(err) {
+				continue
+			}
+			return err
+		}
+		if err := in.add(obj); err != nil {
+			return err
+		}
+	}
+}
+
+// add adds the object, or the items of the list, to the input
+func (in *Input) add(obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *corev1.Node:
+		in.Nodes = append(in.Nodes, o)
+	case *corev1.Pod:
+		in.Pods = append(in.Pods, o)
+	case *corev1.Namespace:
+		in.Namespaces = append(in.Namespaces, o)
+	case *v1alpha1.PlacementPolicy:
+		in.PlacementPolicies = append(in.PlacementPolicies, o)
+	case *v1alpha1.ClusterPlacementPolicy:
+		in.ClusterPlacementPolicies = append(in.ClusterPlacementPolicies, o)
+	case *corev1.List:
+		for _, item := range o.Items {
+			itemObj, _, err := codecs.UniversalDeserializer().Decode(item.Raw, nil, nil)
+			if err != nil {
+				if runtime.IsNotRegisteredError(err) {
+					continue
+				}
+				return err
+			}
+			if err := in.add(itemObj); err != nil {
+				return err
+			}
+		}
+	default:
+		if meta.IsListType(obj) {
+			items, err := meta.ExtractList(obj)
+			if err != nil {
+				return err
+			}
+			for _, item := range items {
+				if err := in.add(item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
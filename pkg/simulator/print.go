@@ -0,0 +1,108 @@
+package simulator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Print writes the result of the simulation in a human readable format
+func (r *Result) Print(out io.Writer) error {
+	if r.PlacementPolicy == nil {
+		fmt.Fprintf(out, "No placement policy matches the replicas.\n\n")
+	} else if r.NodeGroups != nil {
+		pp := r.PlacementPolicy
+		targetSizes := make([]string, 0, len(pp.Spec.Policy.Groups))
+		for _, group := range pp.Spec.Policy.Groups {
+			targetSizes = append(targetSizes, group.Name+"="+group.TargetSize.String())
+		}
+		fmt.Fprintf(out, "Placement policy %s/%s (%s, %s %s on node groups)\n\n", pp.Namespace, pp.Name,
+			pp.Spec.EnforcementMode, pp.Spec.Policy.Action, strings.Join(targetSizes, ","))
+	} else {
+		pp := r.PlacementPolicy
+		targetSize := pp.Spec.Policy.TargetSize.String()
+		if pp.Spec.Policy.MinSize != nil {
+			targetSize += fmt.Sprintf(", min %d", *pp.Spec.Policy.MinSize)
+		}
+		if pp.Spec.Policy.MaxSize != nil {
+			targetSize += fmt.Sprintf(", max %d", *pp.Spec.Policy.MaxSize)
+		}
+		fmt.Fprintf(out, "Placement policy %s/%s (%s, %s %s on matching nodes)\n\n", pp.Namespace, pp.Name,
+			pp.Spec.EnforcementMode, pp.Spec.Policy.Action, targetSize)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "REPLICA\tNODE\t%s\tSCORE\n", r.nodeColumnHeader())
+	replicasOnNode := make(map[string]int)
+	replicasInGroup := make(map[string]int)
+	var unschedulable, onMatchingNodes int
+	for _, placement := range r.Placements {
+		if placement.NodeName == "" {
+			unschedulable++
+			fmt.Fprintf(w, "%s\t<none>\t\t\t%s\n", placement.Pod.Name, placement.Reason)
+			continue
+		}
+		replicasOnNode[placement.NodeName]++
+		if r.NodeWithMatchingLabels[placement.NodeName] {
+			onMatchingNodes++
+		}
+		if group, ok := r.NodeGroups[placement.NodeName]; ok {
+			replicasInGroup[group]++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", placement.Pod.Name, placement.NodeName,
+			r.nodeColumn(placement.NodeName), placement.Score)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "NODE\t%s\tREPLICAS\n", r.nodeColumnHeader())
+	for _, node := range r.Nodes {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", node.Name, r.nodeColumn(node.Name), replicasOnNode[node.Name])
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Replicas:\t%d\n", len(r.Placements))
+	fmt.Fprintf(w, "Unschedulable:\t%d\n", unschedulable)
+	switch {
+	case r.NodeGroups != nil:
+		onOtherNodes := len(r.Placements) - unschedulable
+		for _, group := range r.PlacementPolicy.Spec.Policy.Groups {
+			fmt.Fprintf(w, "On node group %s:\t%d\n", group.Name, replicasInGroup[group.Name])
+			onOtherNodes -= replicasInGroup[group.Name]
+		}
+		fmt.Fprintf(w, "On other nodes:\t%d\n", onOtherNodes)
+	case r.PlacementPolicy != nil:
+		fmt.Fprintf(w, "On matching nodes:\t%d\n", onMatchingNodes)
+	}
+	return w.Flush()
+}
+
+func (r *Result) nodeColumnHeader() string {
+	if r.NodeGroups != nil {
+		return "NODE GROUP"
+	}
+	return "MATCHES NODE SELECTOR"
+}
+
+// nodeColumn returns the node group of the node for placement policies with node
+// groups, and whether the node matches the node selector otherwise
+func (r *Result) nodeColumn(nodeName string) string {
+	if r.PlacementPolicy == nil {
+		return "-"
+	}
+	if r.NodeGroups != nil {
+		if group, ok := r.NodeGroups[nodeName]; ok {
+			return group
+		}
+		return "<none>"
+	}
+	return fmt.Sprintf("%t", r.NodeWithMatchingLabels[nodeName])
+}
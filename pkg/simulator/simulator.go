@@ -0,0 +1,257 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	configscheme "github.com/Azure/placement-policy-scheduler-plugins/apis/config/scheme"
+	configv1beta1 "github.com/Azure/placement-policy-scheduler-plugins/apis/config/v1beta1"
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+	ppfake "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/clientset/versioned/fake"
+	ppinformers "github.com/Azure/placement-policy-scheduler-plugins/pkg/client/informers/externalversions"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy"
+	"github.com/Azure/placement-policy-scheduler-plugins/pkg/plugins/placementpolicy/core"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+// Placement is the simulated placement of a replica.
+type Placement struct {
+	// Pod is the replica
+	Pod *corev1.Pod
+	// NodeName is the node the replica is placed on, empty if the replica is unschedulable
+	NodeName string
+	// Score is the score of the node given by the placement policy plugin
+	Score int64
+	// Reason is the reason the replica is unschedulable
+	Reason string
+}
+
+// Result is the result of a simulation.
+type Result struct {
+	// PlacementPolicy is the placement policy applied to the replicas, nil if no
+	// placement policy matches them
+	PlacementPolicy *v1alpha1.PlacementPolicy
+	// Nodes are the nodes of the cluster, sorted by name
+	Nodes []*corev1.Node
+	// NodeWithMatchingLabels is the set of nodes matching the node selector of the
+	// placement policy
+	NodeWithMatchingLabels map[string]bool
+	// NodeGroups are the names of the node groups of the placement policy the nodes
+	// belong to, nil if the placement policy doesn't have node groups
+	NodeGroups map[string]string
+	// Placements are the placements of the replicas, in the order they were scheduled
+	Placements []Placement
+}
+
+// Simulate schedules the given number of replicas of the pod template one after the other
+// with the placement policy plugin, against the cluster state of the input. Only the
+// placement policy plugin runs: the resources, taints and affinities of the pods and nodes
+// are not taken into account. Among the nodes with the highest score, the node with the
+// fewest replicas is picked to approximate the spreading of the default scheduler plugins.
+func Simulate(ctx context.Context, input *Input, template *corev1.Pod, replicas int) (*Result, error) {
+	client := fake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	namespaceInformer := informerFactory.Core().V1().Namespaces()
+	ppClient := ppfake.NewSimpleClientset()
+	ppInformerFactory := ppinformers.NewSharedInformerFactory(ppClient, 0)
+	ppInformer := ppInformerFactory.Placementpolicy().V1alpha1().PlacementPolicies()
+	cppInformer := ppInformerFactory.Placementpolicy().V1alpha1().ClusterPlacementPolicies()
+
+	// the informers are never started, the listers read the objects of the input
+	podIndexer := podInformer.Informer().GetIndexer()
+	for _, pod := range input.Pods {
+		if err := podIndexer.Add(pod); err != nil {
+			return nil, err
+		}
+	}
+	for _, namespace := range input.Namespaces {
+		if err := namespaceInformer.Informer().GetIndexer().Add(namespace); err != nil {
+			return nil, err
+		}
+	}
+	for _, pp := range input.PlacementPolicies {
+		if err := ppInformer.Informer().GetIndexer().Add(pp); err != nil {
+			return nil, err
+		}
+	}
+	for _, cpp := range input.ClusterPlacementPolicies {
+		if err := cppInformer.Informer().GetIndexer().Add(cpp); err != nil {
+			return nil, err
+		}
+	}
+
+	args := &configv1beta1.PlacementPolicyArgs{}
+	configscheme.Scheme.Default(args)
+	// the annotations would be written with the API client
+	annotatePods := false
+	args.AnnotatePods = &annotatePods
+
+	nodes := make([]*corev1.Node, len(input.Nodes))
+	copy(nodes, input.Nodes)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	snapshot := newSnapshot(nodes)
+	handle, err := frameworkruntime.NewFramework(nil, nil, frameworkruntime.WithSnapshotSharedLister(snapshot))
+	if err != nil {
+		return nil, err
+	}
+	ppMgr := core.NewPlacementPolicyManager(nil, nil, snapshot, ppInformer, cppInformer,
+		podInformer.Lister(), namespaceInformer.Lister(), *args.DefaultEnforcementMode)
+	plugin := placementpolicy.NewPlugin(handle, ppMgr, args)
+
+	// the replicas are created before they are scheduled, as by a workload controller
+	pods := newReplicas(template, replicas)
+	for _, pod := range pods {
+		if err := podIndexer.Add(pod); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &Result{Nodes: nodes, NodeWithMatchingLabels: make(map[string]bool)}
+	if len(pods) > 0 {
+		pp, err := ppMgr.GetPlacementPolicyForPod(ctx, pods[0])
+		if err != nil {
+			return nil, err
+		}
+		if pp != nil && len(pp.Spec.Policy.Groups) > 0 {
+			result.PlacementPolicy = pp
+			groupSelectors, err := core.GetNodeGroupSelectors(pp)
+			if err != nil {
+				return nil, err
+			}
+			result.NodeGroups = make(map[string]string)
+			for _, node := range nodes {
+				if group := core.GetNodeGroup(node, groupSelectors); group >= 0 {
+					result.NodeGroups[node.Name] = pp.Spec.Policy.Groups[group].Name
+				}
+			}
+		} else if pp != nil {
+			result.PlacementPolicy = pp
+			nodeSelector, err := metav1.LabelSelectorAsSelector(pp.Spec.NodeSelector)
+			if err != nil {
+				return nil, err
+			}
+			for _, node := range nodes {
+				if nodeSelector.Matches(labels.Set(node.Labels)) {
+					result.NodeWithMatchingLabels[node.Name] = true
+				}
+			}
+		}
+	}
+
+	replicasOnNode := make(map[string]int)
+	for _, pod := range pods {
+		placement, err := schedule(ctx, plugin, nodes, pod, replicasOnNode)
+		if err != nil {
+			return nil, err
+		}
+		result.Placements = append(result.Placements, placement)
+		if placement.NodeName == "" {
+			continue
+		}
+		// bind the replica, so it's counted on its node when scheduling the next ones
+		replicasOnNode[placement.NodeName]++
+		bound := pod.DeepCopy()
+		bound.Spec.NodeName = placement.NodeName
+		if err := podIndexer.Update(bound); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// schedule runs a scheduling cycle of the placement policy plugin for the pod.
+func schedule(ctx context.Context, plugin *placementpolicy.Plugin, nodes []*corev1.Node, pod *corev1.Pod, replicasOnNode map[string]int) (Placement, error) {
+	placement := Placement{Pod: pod}
+	state := framework.NewCycleState()
+	if status := plugin.PreFilter(ctx, state, pod); !status.IsSuccess() {
+		return placement, status.AsError()
+	}
+
+	var feasibleNodes []*corev1.Node
+	reasons := make(map[string]bool)
+	for _, node := range nodes {
+		nodeInfo := framework.NewNodeInfo()
+		nodeInfo.SetNode(node)
+		status := plugin.Filter(ctx, state, pod, nodeInfo)
+		if status.Code() == framework.Error {
+			return placement, status.AsError()
+		}
+		if !status.IsSuccess() {
+			reasons[status.Message()] = true
+			continue
+		}
+		feasibleNodes = append(feasibleNodes, node)
+	}
+	if len(feasibleNodes) == 0 {
+		messages := make([]string, 0, len(reasons))
+		for reason := range reasons {
+			messages = append(messages, reason)
+		}
+		sort.Strings(messages)
+		placement.Reason = fmt.Sprintf("0/%d nodes are available: %s", len(nodes), strings.Join(messages, ", "))
+		return placement, nil
+	}
+
+	if status := plugin.PreScore(ctx, state, pod, feasibleNodes); !status.IsSuccess() {
+		return placement, status.AsError()
+	}
+	for _, node := range feasibleNodes {
+		score, status := plugin.Score(ctx, state, pod, node.Name)
+		if !status.IsSuccess() {
+			return placement, status.AsError()
+		}
+		if placement.NodeName == "" || score > placement.Score ||
+			score == placement.Score && replicasOnNode[node.Name] < replicasOnNode[placement.NodeName] {
+			placement.NodeName, placement.Score = node.Name, score
+		}
+	}
+
+	if status := plugin.Reserve(ctx, state, pod, placement.NodeName); !status.IsSuccess() {
+		return placement, status.AsError()
+	}
+	return placement, nil
+}
+
+// newReplicas returns the replicas of the pod template. The replicas are controlled by
+// the owner of the template or, if it doesn't have one, by the same simulated owner.
+func newReplicas(template *corev1.Pod, replicas int) []*corev1.Pod {
+	name := template.GenerateName
+	if name == "" {
+		name = template.Name + "-"
+	}
+	owner := metav1.GetControllerOf(template)
+	if owner == nil {
+		controller := true
+		owner = &metav1.OwnerReference{
+			APIVersion: "apps/v1",
+			Kind:       "ReplicaSet",
+			Name:       template.Name,
+			UID:        types.UID("simulated-" + template.Name),
+			Controller: &controller,
+		}
+	}
+
+	pods := make([]*corev1.Pod, 0, replicas)
+	for i := 0; i < replicas; i++ {
+		pod := template.DeepCopy()
+		pod.Name = fmt.Sprintf("%s%d", name, i)
+		pod.GenerateName = ""
+		pod.UID = types.UID(fmt.Sprintf("simulated-%s/%s", pod.Namespace, pod.Name))
+		pod.OwnerReferences = []metav1.OwnerReference{*owner}
+		pod.Spec.NodeName = ""
+		pod.Status = corev1.PodStatus{Phase: corev1.PodPending}
+		pods = append(pods, pod)
+	}
+	return pods
+}
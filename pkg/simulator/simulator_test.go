@@ -0,0 +1,290 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/placement-policy-scheduler-plugins/apis/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const testInput = `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: node1
+    labels:
+      node: want
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: node2
+    labels:
+      node: unwant
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: node3
+    labels:
+      node: unwant
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: ignored
+    namespace: default
+---
+apiVersion: placement-policy.scheduling.x-k8s.io/v1alpha1
+kind: PlacementPolicy
+metadata:
+  name: besteffort-must
+  namespace: default
+spec:
+  weight: 200
+  enforcementMode: BestEffort
+  podSelector:
+    matchLabels:
+      app: nginx
+  nodeSelector:
+    matchLabels:
+      node: want
+  policy:
+    action: Must
+    targetSize: 40%
+`
+
+func TestLoad(t *testing.T) {
+	input := &Input{}
+	if err := input.load(strings.NewReader(testInput)); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if len(input.Nodes) != 3 || len(input.PlacementPolicies) != 1 || len(input.Pods) != 0 {
+		t.Errorf("load() = %d nodes, %d placement policies, %d pods, want 3, 1, 0",
+			len(input.Nodes), len(input.PlacementPolicies), len(input.Pods))
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	template := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", Labels: map[string]string{"app": "nginx"}},
+	}
+
+	tests := []struct {
+		name                string
+		enforcementMode     v1alpha1.EnforcementMode
+		action              v1alpha1.Action
+		noPolicy            bool
+		wantOnMatchingNodes int
+		wantUnschedulable   int
+	}{
+		{
+			name:                "best effort must",
+			enforcementMode:     v1alpha1.EnforcementModeBestEffort,
+			action:              v1alpha1.ActionMust,
+			wantOnMatchingNodes: 4,
+		},
+		{
+			name:                "strict must",
+			enforcementMode:     v1alpha1.EnforcementModeStrict,
+			action:              v1alpha1.ActionMust,
+			wantOnMatchingNodes: 4,
+		},
+		{
+			name:                "strict must not",
+			enforcementMode:     v1alpha1.EnforcementModeStrict,
+			action:              v1alpha1.ActionMustNot,
+			wantOnMatchingNodes: 6,
+		},
+		{
+			name:     "no placement policy",
+			noPolicy: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &Input{}
+			if err := input.load(strings.NewReader(testInput)); err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			if tt.noPolicy {
+				input.PlacementPolicies = nil
+			} else {
+				input.PlacementPolicies[0].Spec.EnforcementMode = tt.enforcementMode
+				input.PlacementPolicies[0].Spec.Policy.Action = tt.action
+				targetSize := intstr.FromString("40%")
+				input.PlacementPolicies[0].Spec.Policy.TargetSize = &targetSize
+			}
+
+			result, err := Simulate(context.Background(), input, template, 10)
+			if err != nil {
+				t.Fatalf("Simulate() error = %v", err)
+			}
+			if len(result.Placements) != 10 {
+				t.Fatalf("Simulate() placed %d replicas, want 10", len(result.Placements))
+			}
+			var onMatchingNodes, unschedulable int
+			for _, placement := range result.Placements {
+				switch {
+				case placement.NodeName == "":
+					unschedulable++
+				case result.NodeWithMatchingLabels[placement.NodeName]:
+					onMatchingNodes++
+				}
+			}
+			if onMatchingNodes != tt.wantOnMatchingNodes || unschedulable != tt.wantUnschedulable {
+				t.Errorf("Simulate() placed %d replicas on matching nodes, %d unschedulable, want %d, %d",
+					onMatchingNodes, unschedulable, tt.wantOnMatchingNodes, tt.wantUnschedulable)
+			}
+
+			var out bytes.Buffer
+			if err := result.Print(&out); err != nil {
+				t.Fatalf("Print() error = %v", err)
+			}
+			for _, line := range strings.Split(out.String(), "\n") {
+				if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "Replicas:" && fields[1] != "10" {
+					t.Errorf("Print() reports %s replicas, want 10:\n%s", fields[1], out.String())
+				}
+			}
+			if !strings.Contains(out.String(), "NODE   MATCHES NODE SELECTOR  REPLICAS") {
+				t.Errorf("Print() output doesn't contain the placement per node:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestSimulateTopologySpread(t *testing.T) {
+	makeNode := func(name string, matches bool, zone string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+			"node":                        "unwant",
+			"topology.kubernetes.io/zone": zone,
+		}}}
+		if matches {
+			node.Labels["node"] = "want"
+		}
+		return node
+	}
+	targetSize := intstr.FromString("40%")
+	input := &Input{
+		Nodes: []*corev1.Node{
+			makeNode("node1", true, "zone1"),
+			makeNode("node2", true, "zone1"),
+			makeNode("node3", true, "zone2"),
+			makeNode("node4", false, "zone1"),
+		},
+		PlacementPolicies: []*v1alpha1.PlacementPolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "default"},
+			Spec: v1alpha1.PlacementPolicySpec{
+				Weight:          v1alpha1.DefaultWeight,
+				EnforcementMode: v1alpha1.EnforcementModeStrict,
+				PodSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+				NodeSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"node": "want"}},
+				Policy: &v1alpha1.Policy{
+					Action:      v1alpha1.ActionMust,
+					TargetSize:  &targetSize,
+					Scope:       v1alpha1.ScopeAll,
+					TopologyKey: "topology.kubernetes.io/zone",
+				},
+			},
+		}},
+	}
+	template := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", Labels: map[string]string{"app": "nginx"}},
+	}
+
+	for _, mode := range []v1alpha1.EnforcementMode{v1alpha1.EnforcementModeStrict, v1alpha1.EnforcementModeBestEffort} {
+		t.Run(string(mode), func(t *testing.T) {
+			input.PlacementPolicies[0].Spec.EnforcementMode = mode
+			result, err := Simulate(context.Background(), input, template, 10)
+			if err != nil {
+				t.Fatalf("Simulate() error = %v", err)
+			}
+			zones := make(map[string]string)
+			for _, node := range input.Nodes {
+				zones[node.Name] = node.Labels["topology.kubernetes.io/zone"]
+			}
+			replicasInZone := make(map[string]int)
+			for _, placement := range result.Placements {
+				if result.NodeWithMatchingLabels[placement.NodeName] {
+					replicasInZone[zones[placement.NodeName]]++
+				}
+			}
+			if replicasInZone["zone1"] != 2 || replicasInZone["zone2"] != 2 {
+				t.Errorf("Simulate() placed %v replicas on matching nodes per zone, want 2 in zone1 and zone2", replicasInZone)
+			}
+		})
+	}
+}
+
+func TestSimulateNodeGroups(t *testing.T) {
+	makeNode := func(name, pool string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": pool}}}
+	}
+	makeGroup := func(name, targetSize string) v1alpha1.NodeGroup {
+		size := intstr.FromString(targetSize)
+		return v1alpha1.NodeGroup{
+			Name:         name,
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": name}},
+			TargetSize:   &size,
+		}
+	}
+	input := &Input{
+		Nodes: []*corev1.Node{
+			makeNode("node1", "ondemand"),
+			makeNode("node2", "spot"),
+			makeNode("node3", "reserved"),
+			makeNode("node4", "other"),
+		},
+		PlacementPolicies: []*v1alpha1.PlacementPolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "pp", Namespace: "default"},
+			Spec: v1alpha1.PlacementPolicySpec{
+				Weight:          v1alpha1.DefaultWeight,
+				EnforcementMode: v1alpha1.EnforcementModeStrict,
+				PodSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+				Policy: &v1alpha1.Policy{
+					Action: v1alpha1.ActionMust,
+					Scope:  v1alpha1.ScopeAll,
+					Groups: []v1alpha1.NodeGroup{
+						makeGroup("ondemand", "50%"),
+						makeGroup("spot", "30%"),
+						makeGroup("reserved", "20%"),
+					},
+				},
+			},
+		}},
+	}
+	template := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", Labels: map[string]string{"app": "nginx"}},
+	}
+
+	result, err := Simulate(context.Background(), input, template, 10)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+	replicasInGroup := make(map[string]int)
+	for _, placement := range result.Placements {
+		replicasInGroup[result.NodeGroups[placement.NodeName]]++
+	}
+	want := map[string]int{"ondemand": 5, "spot": 3, "reserved": 2}
+	for group, replicas := range want {
+		if replicasInGroup[group] != replicas {
+			t.Errorf("Simulate() placed %v replicas per node group, want %v", replicasInGroup, want)
+			break
+		}
+	}
+
+	var out bytes.Buffer
+	if err := result.Print(&out); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "NODE GROUP") {
+		t.Errorf("Print() output doesn't contain the node group column:\n%s", out.String())
+	}
+}
@@ -0,0 +1,51 @@
+package simulator
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// snapshot is the node snapshot of the simulated scheduler
+type snapshot struct {
+	nodeInfos []*framework.NodeInfo
+}
+
+var _ framework.SharedLister = &snapshot{}
+var _ framework.NodeInfoLister = &snapshot{}
+
+func newSnapshot(nodes []*corev1.Node) *snapshot {
+	s := &snapshot{}
+	for _, node := range nodes {
+		nodeInfo := framework.NewNodeInfo()
+		nodeInfo.SetNode(node)
+		s.nodeInfos = append(s.nodeInfos, nodeInfo)
+	}
+	return s
+}
+
+func (s *snapshot) NodeInfos() framework.NodeInfoLister {
+	return s
+}
+
+func (s *snapshot) List() ([]*framework.NodeInfo, error) {
+	return s.nodeInfos, nil
+}
+
+func (s *snapshot) HavePodsWithAffinityList() ([]*framework.NodeInfo, error) {
+	return nil, nil
+}
+
+func (s *snapshot) HavePodsWithRequiredAntiAffinityList() ([]*framework.NodeInfo, error) {
+	return nil, nil
+}
+
+func (s *snapshot) Get(nodeName string) (*framework.NodeInfo, error) {
+	for _, nodeInfo := range s.nodeInfos {
+		if nodeInfo.Node().Name == nodeName {
+			return nodeInfo, nil
+		}
+	}
+	return nil, fmt.Errorf("nodeinfo not found for node name %q", nodeName)
+}
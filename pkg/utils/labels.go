@@ -1,15 +1,16 @@
 package utils
 
-// HasMatchingLabels checks if the labels exist in the provided set
-func HasMatchingLabels(l, wantLabels map[string]string) bool {
-	if len(l) < len(wantLabels) {
-		return false
-	}
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
 
-	for k, v := range wantLabels {
-		if l[k] != v {
-			return false
-		}
+// HasMatchingLabels checks if the labels satisfy the label selector.
+// Both matchLabels and matchExpressions of the selector are evaluated.
+func HasMatchingLabels(l map[string]string, selector *metav1.LabelSelector) (bool, error) {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
 	}
-	return true
+	return s.Matches(labels.Set(l)), nil
 }
@@ -1,27 +1,43 @@
 package utils
 
-import "testing"
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 func TestHasMatchingLabels(t *testing.T) {
 	test := []struct {
-		name          string
-		l, wantLabels map[string]string
-		want          bool
+		name     string
+		l        map[string]string
+		selector *metav1.LabelSelector
+		want     bool
+		wantErr  bool
 	}{
 		{
-			name:       "no labels",
-			l:          map[string]string{},
-			wantLabels: map[string]string{},
-			want:       true,
+			name:     "no labels",
+			l:        map[string]string{},
+			selector: &metav1.LabelSelector{},
+			want:     true,
 		},
 		{
-			name: "actual labels is less than want labels",
+			name: "nil selector matches nothing",
 			l: map[string]string{
 				"foo": "bar",
 			},
-			wantLabels: map[string]string{
+			selector: nil,
+			want:     false,
+		},
+		{
+			name: "actual labels is less than want labels",
+			l: map[string]string{
 				"foo": "bar",
-				"baz": "qux",
+			},
+			selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"foo": "bar",
+					"baz": "qux",
+				},
 			},
 			want: false,
 		},
@@ -30,8 +46,10 @@ func TestHasMatchingLabels(t *testing.T) {
 			l: map[string]string{
 				"foo": "bar",
 			},
-			wantLabels: map[string]string{
-				"baz": "qux",
+			selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"baz": "qux",
+				},
 			},
 			want: false,
 		},
@@ -41,9 +59,11 @@ func TestHasMatchingLabels(t *testing.T) {
 				"foo": "bar",
 				"baz": "qux",
 			},
-			wantLabels: map[string]string{
-				"foo": "bar",
-				"baz": "qux",
+			selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"foo": "bar",
+					"baz": "qux",
+				},
 			},
 			want: true,
 		},
@@ -53,18 +73,110 @@ func TestHasMatchingLabels(t *testing.T) {
 				"foo": "bar",
 				"baz": "qux",
 			},
-			wantLabels: map[string]string{
+			selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"foo": "bar",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "match expression with In operator",
+			l: map[string]string{
+				"foo": "bar",
+			},
+			selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "foo", Operator: metav1.LabelSelectorOpIn, Values: []string{"bar", "baz"}},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "match expression with NotIn operator",
+			l: map[string]string{
+				"foo": "bar",
+			},
+			selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "foo", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"bar"}},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "match expression with Exists operator",
+			l: map[string]string{
+				"kubernetes.azure.com/scalesetpriority": "spot",
+			},
+			selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "kubernetes.azure.com/scalesetpriority", Operator: metav1.LabelSelectorOpExists},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "match expression with Exists operator and missing key",
+			l: map[string]string{
 				"foo": "bar",
 			},
+			selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "kubernetes.azure.com/scalesetpriority", Operator: metav1.LabelSelectorOpExists},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "match expression with DoesNotExist operator",
+			l: map[string]string{
+				"foo": "bar",
+			},
+			selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "kubernetes.azure.com/scalesetpriority", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
 			want: true,
 		},
+		{
+			name: "match labels and match expressions are ANDed",
+			l: map[string]string{
+				"foo": "bar",
+			},
+			selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"foo": "bar",
+				},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "baz", Operator: metav1.LabelSelectorOpExists},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "invalid operator",
+			l: map[string]string{
+				"foo": "bar",
+			},
+			selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "foo", Operator: "Invalid"},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range test {
 		t.Run(tt.name, func(t *testing.T) {
-			got := HasMatchingLabels(tt.l, tt.wantLabels)
+			got, err := HasMatchingLabels(tt.l, tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HasMatchingLabels(%v, %v) error = %v, wantErr %v", tt.l, tt.selector, err, tt.wantErr)
+			}
 			if got != tt.want {
-				t.Errorf("HasMatchingLabels(%v, %v) = %v, want %v", tt.l, tt.wantLabels, got, tt.want)
+				t.Errorf("HasMatchingLabels(%v, %v) = %v, want %v", tt.l, tt.selector, got, tt.want)
 			}
 		})
 	}
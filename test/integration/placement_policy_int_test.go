@@ -57,8 +57,10 @@ func TestPlacementPolicyPlugins(t *testing.T) {
 
 	t.Log("Creating CRD...")
 	apiExtensionClient := apiextensionsclient.NewForConfigOrDie(server.ClientConfig)
-	if _, err := apiExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Create(ctx, makePlacementPolicyCRD(), metav1.CreateOptions{}); err != nil {
-		t.Fatal(err)
+	for _, crd := range []string{"placementpolicies", "clusterplacementpolicies"} {
+		if _, err := apiExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Create(ctx, makeCRD(crd), metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
 	}
 
 	server.ClientConfig.ContentType = "application/json"
@@ -90,8 +92,10 @@ func TestPlacementPolicyPlugins(t *testing.T) {
 
 	cfg.Profiles[0].Plugins.PreFilter.Enabled = append(cfg.Profiles[0].Plugins.PreFilter.Enabled, schedapi.Plugin{Name: placementpolicy.Name})
 	cfg.Profiles[0].Plugins.Filter.Enabled = append(cfg.Profiles[0].Plugins.Filter.Enabled, schedapi.Plugin{Name: placementpolicy.Name})
+	cfg.Profiles[0].Plugins.PostFilter.Enabled = append(cfg.Profiles[0].Plugins.PostFilter.Enabled, schedapi.Plugin{Name: placementpolicy.Name})
 	cfg.Profiles[0].Plugins.PreScore.Enabled = append(cfg.Profiles[0].Plugins.PreScore.Enabled, schedapi.Plugin{Name: placementpolicy.Name})
 	cfg.Profiles[0].Plugins.Score.Enabled = append(cfg.Profiles[0].Plugins.Score.Enabled, schedapi.Plugin{Name: placementpolicy.Name})
+	cfg.Profiles[0].Plugins.Reserve.Enabled = append(cfg.Profiles[0].Plugins.Reserve.Enabled, schedapi.Plugin{Name: placementpolicy.Name})
 
 	testCtx = InitTestSchedulerWithOptions(
 		t,
@@ -236,21 +240,21 @@ func getNodeName(c kubernetes.Interface, podNamespace, podName string) (string,
 	return pod.Spec.NodeName, nil
 }
 
-func makePlacementPolicyCRD() *apiextensionsv1.CustomResourceDefinition {
-	content, err := os.ReadFile("../../config/crd/bases/placement-policy.scheduling.x-k8s.io_placementpolicies.yaml")
+func makeCRD(plural string) *apiextensionsv1.CustomResourceDefinition {
+	content, err := os.ReadFile("../../config/crd/bases/placement-policy.scheduling.x-k8s.io_" + plural + ".yaml")
 	if err != nil {
 		klog.ErrorS(err, "Cannot read the yaml file")
 		return &apiextensionsv1.CustomResourceDefinition{}
 	}
 
-	placementPoliciesCRD := &apiextensionsv1.CustomResourceDefinition{}
-	err = yaml.Unmarshal(content, placementPoliciesCRD)
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	err = yaml.Unmarshal(content, crd)
 	if err != nil {
 		klog.ErrorS(err, "Cannot parse the yaml file")
 		return &apiextensionsv1.CustomResourceDefinition{}
 	}
 
-	return placementPoliciesCRD
+	return crd
 }
 
 func createPlacementPolicy(ctx context.Context, client versioned.Interface, placementpolicy *v1alpha1.PlacementPolicy) error {
@@ -35,7 +35,7 @@ func MakePlacementPolicy(mode v1alpha1.EnforcementMode, targetSize intstr.IntOrS
 	return &v1alpha1.PlacementPolicy{
 		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
 		Spec: v1alpha1.PlacementPolicySpec{
-			Weight:          100,
+			Weight:          200,
 			EnforcementMode: mode,
 			PodSelector: &metav1.LabelSelector{
 				MatchLabels: PodSelectorLabels,